@@ -1,9 +1,181 @@
 package filter
 
-import "errors"
+import (
+	"errors"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/file"
+	"github.com/expr-lang/expr/parser/lexer"
+
+	"github.com/happening-oss/expr2sql/pkg/filter/internal"
+)
 
 var ErrInvalidFilter = errors.New("invalid filter")
 
+// ErrInvalidIdentifier is returned for identifiers whose database names are not plain column names
+var ErrInvalidIdentifier = errors.New("invalid identifier")
+
+// ErrorCode is a stable machine-readable identifier of the TranslationError cause
+type ErrorCode string
+
+const (
+	ErrorCodeParsingError         ErrorCode = "parsing_error"
+	ErrorCodeUnknownIdentifier    ErrorCode = "unknown_identifier"
+	ErrorCodeUnsupportedOperation ErrorCode = "unsupported_operation"
+	ErrorCodeInvalidFilter        ErrorCode = "invalid_filter"
+)
+
+// Position is a 1-based line and column of a character within the query
+type Position struct {
+	Line   int
+	Column int
+}
+
+// TranslationError is returned for every query which cannot be translated. It wraps the specific cause,
+// so IsParsingError, IsUnknownIdentifier, IsUnsupportedOperation and errors.Is(err, ErrInvalidFilter) keep working.
+type TranslationError struct {
+	Code    ErrorCode
+	Start   Position // first character of the offending expression
+	End     Position // character after the last one of the offending expression
+	Snippet string   // offending expression as written in the query
+
+	Expected [][]string // accepted combinations of operator operand types, or accepted types of each function argument
+	Actual   []string   // operand types found in the query, when the operand types are not supported
+
+	Err error
+
+	location file.Location
+}
+
+func (e *TranslationError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TranslationError) Unwrap() error {
+	return e.Err
+}
+
+func newTranslationError(code ErrorCode, node ast.Node, err error) *TranslationError {
+	return &TranslationError{Code: code, Err: err, location: nodeLocation(node)}
+}
+
+// withTypes records the accepted and the actual operand types of the failed operation
+func (e *TranslationError) withTypes(expected [][]internal.ExprType, actual ...internal.ExprType) *TranslationError {
+	e.Expected = make([][]string, 0, len(expected))
+	for _, types := range expected {
+		e.Expected = append(e.Expected, typeNames(types))
+	}
+	e.Actual = typeNames(actual)
+	return e
+}
+
+func typeNames(types []internal.ExprType) []string {
+	names := make([]string, 0, len(types))
+	for _, t := range types {
+		names = append(names, string(t))
+	}
+	return names
+}
+
+// bind resolves the location of the error within the query. Parsing errors are located at the offending token,
+// while other errors span the offending expression along with its parentheses.
+func (e *TranslationError) bind(source file.Source) {
+	from, to := max(0, min(e.location.From, len(source))), max(0, min(e.location.To, len(source)))
+	if e.Code != ErrorCodeParsingError {
+		from, to = balanceParentheses(source, from, to)
+	}
+	e.Start = position(source, from)
+	e.End = position(source, to)
+	e.Snippet = string(source[from:to])
+}
+
+// bindSource resolves the location of a TranslationError within the query
+func bindSource(err error, query string) error {
+	var e *TranslationError
+	if errors.As(err, &e) {
+		e.bind(file.NewSource(query))
+	}
+	return err
+}
+
+// nodeLocation spans the tokens of the node and all of its descendants
+func nodeLocation(node ast.Node) file.Location {
+	v := &locationVisitor{location: node.Location()}
+	ast.Walk(&node, v)
+	return v.location
+}
+
+type locationVisitor struct {
+	location file.Location
+}
+
+func (v *locationVisitor) Visit(node *ast.Node) {
+	location := (*node).Location()
+	if location.From == location.To {
+		return
+	}
+	v.location.From = min(v.location.From, location.From)
+	v.location.To = max(v.location.To, location.To)
+}
+
+// balanceParentheses extends the span over the parentheses and brackets which are opened or closed within it,
+// e.g. over the closing parenthesis of a function call, which has no node of its own
+func balanceParentheses(source file.Source, from, to int) (int, int) {
+	unclosed, unopened := 0, 0
+	var quote rune
+	for _, r := range source[from:to] {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'' || r == '`':
+			quote = r
+		case r == '(' || r == '[':
+			unclosed++
+		case r == ')' || r == ']':
+			if unclosed > 0 {
+				unclosed--
+			} else {
+				unopened++
+			}
+		}
+	}
+	for ; unopened > 0 && from > 0; from-- {
+		switch source[from-1] {
+		case '(', '[':
+			unopened--
+		case ')', ']':
+			unopened++
+		}
+	}
+	for ; unclosed > 0 && to < len(source); to++ {
+		switch source[to] {
+		case ')', ']':
+			unclosed--
+		case '(', '[':
+			unclosed++
+		}
+	}
+	return from, to
+}
+
+func position(source file.Source, offset int) Position {
+	p := Position{Line: 1, Column: 1}
+	for _, r := range source[:offset] {
+		if r == '\n' {
+			p.Line++
+			p.Column = 1
+		} else {
+			p.Column++
+		}
+	}
+	return p
+}
+
 type ParsingError struct {
 	Err error
 }
@@ -12,6 +184,38 @@ func (e *ParsingError) Error() string {
 	return "parsing_error: " + e.Err.Error()
 }
 
+// parsingError locates the error in parsing the input at the offending token, where an unexpected end
+// of the input is located after its last character
+func parsingError(err error, input string) *TranslationError {
+	e := &TranslationError{Code: ErrorCodeParsingError, Err: &ParsingError{err}}
+	var fileErr *file.Error
+	if errors.As(err, &fileErr) {
+		e.location = fileErr.Location
+		if endOfInput(input, fileErr) {
+			end := utf8.RuneCountInString(input)
+			e.location = file.Location{From: end, To: end}
+		}
+	}
+	return e
+}
+
+// endOfInput reports whether the parser failed at the end of the input, whose EOF token is located
+// at the last character instead of after it
+func endOfInput(input string, fileErr *file.Error) bool {
+	tokens, err := lexer.Lex(file.NewSource(input))
+	if err != nil || len(tokens) == 0 {
+		return false
+	}
+	eof := tokens[len(tokens)-1]
+	if eof.Kind != lexer.EOF || eof.Location != fileErr.Location {
+		return false
+	}
+	ambiguous := slices.ContainsFunc(tokens[:len(tokens)-1], func(token lexer.Token) bool {
+		return token.Location == fileErr.Location
+	})
+	return !ambiguous || strings.Contains(fileErr.Message, string(lexer.EOF)) || strings.Contains(fileErr.Message, "end of expression")
+}
+
 func IsParsingError(err error) bool {
 	if err == nil {
 		return false
@@ -28,8 +232,8 @@ func (e *UnknownIdentifierError) Error() string {
 	return "unknown_identifier: " + e.identifier
 }
 
-func unknownIdentifier(identifier string) error {
-	return &UnknownIdentifierError{identifier}
+func unknownIdentifier(node ast.Node, identifier string) *TranslationError {
+	return newTranslationError(ErrorCodeUnknownIdentifier, node, &UnknownIdentifierError{identifier})
 }
 
 func IsUnknownIdentifier(err error) bool {
@@ -48,8 +252,8 @@ func (e *UnsupportedOperationError) Error() string {
 	return "unsupported_operation: " + e.op
 }
 
-func unsupportedOperation(op string) error {
-	return &UnsupportedOperationError{op}
+func unsupportedOperation(node ast.Node, op string) *TranslationError {
+	return newTranslationError(ErrorCodeUnsupportedOperation, node, &UnsupportedOperationError{op})
 }
 
 func IsUnsupportedOperation(err error) bool {
@@ -0,0 +1,1021 @@
+package filter
+
+import (
+	"bytes"
+	"cmp"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/expr-lang/expr/ast"
+
+	"github.com/happening-oss/expr2sql/pkg/filter/internal"
+)
+
+var ErrDivisionByZero = errors.New("division by zero")
+
+// ErrOutOfRange is returned for arithmetic overflowing 64-bit integers or floats, which PostgreSQL raises as an error
+var ErrOutOfRange = errors.New("value out of range")
+
+// Evaluator evaluates queries against rows in memory, with the semantics of the PostgreSQL condition
+// produced by the Translator: NULL values propagate through operators by three-valued logic and rows
+// for which the condition is NULL do not match. Regular expressions of matches are evaluated with Go RE2,
+// which differs from the regular expressions of databases for some constructs, e.g. \b is a word boundary in RE2
+// and a backspace in PostgreSQL.
+type Evaluator interface {
+	// Compile validates the query with the same type rules as Translator and prepares it for evaluation
+	Compile(query string) (Program, error)
+	// Evaluate compiles the query and reports whether the row matches it
+	Evaluate(query string, row any) (bool, error)
+}
+
+// Program is a compiled query, safe for concurrent use
+type Program interface {
+	// Evaluate reports whether the row matches the query. The row is either a map keyed by expr names or
+	// a struct with fields named by expr tags, as in IdentifiersFromStruct. Missing values are NULL.
+	Evaluate(row any) (bool, error)
+}
+
+func NewEvaluator(allowedIdentifiers []Identifier, opts ...TranslatorOption) Evaluator {
+	t := newTranslator(allowedIdentifiers, postgresDialect{})
+	for _, opt := range opts {
+		opt(t)
+	}
+	return &evaluator{translator: t}
+}
+
+type evaluator struct {
+	translator *translator
+}
+
+func (e *evaluator) Compile(query string) (Program, error) {
+	t := *e.translator
+	t.dynamicTypes = map[ast.Node]internal.ExprType{}
+	t.unified = map[ast.Node]internal.TranslationResult{}
+	parsed, _, err := t.compile(query)
+	if err != nil {
+		return nil, err
+	}
+	v := &compileVisitor{translator: e.translator, regexps: map[string]*regexp.Regexp{}}
+	ast.Walk(&parsed.Node, v)
+	if v.err != nil {
+		return nil, bindSource(v.err, query)
+	}
+	return &program{
+		translator:   e.translator,
+		node:         parsed.Node,
+		regexps:      v.regexps,
+		unified:      t.unified,
+		dynamicTypes: t.dynamicTypes,
+	}, nil
+}
+
+func (e *evaluator) Evaluate(query string, row any) (bool, error) {
+	p, err := e.Compile(query)
+	if err != nil {
+		return false, err
+	}
+	return p.Evaluate(row)
+}
+
+// compileVisitor compiles patterns of the matches operator, which are always string literals,
+// and checks that the custom functions can be evaluated and that no relations are referenced,
+// since rows don't contain their related rows
+type compileVisitor struct {
+	translator *translator
+	regexps    map[string]*regexp.Regexp
+	err        error
+}
+
+func (v *compileVisitor) Visit(node *ast.Node) {
+	if v.err != nil {
+		return
+	}
+	if identifier, ok := (*node).(*ast.IdentifierNode); ok {
+		if id, ok := v.translator.identifier(identifier.Value); ok && id.Type == IdentifierTypeRelation {
+			v.err = unsupportedOperation(*node, fmt.Sprintf("relation %v cannot be evaluated in memory", identifier.Value))
+		}
+		return
+	}
+	if name, _, ok := functionCall(*node); ok {
+		if fn, ok := v.translator.functions[name]; ok && fn.Eval == nil {
+			v.err = unsupportedOperation(*node, fmt.Sprintf("function %v cannot be evaluated in memory", name))
+		}
+		return
+	}
+	binary, ok := (*node).(*ast.BinaryNode)
+	if !ok || binary.Operator != "matches" {
+		return
+	}
+	pattern, ok := binary.Right.(*ast.StringNode)
+	if !ok {
+		return
+	}
+	re, err := regexp.Compile(pattern.Value)
+	if err != nil {
+		v.err = unsupportedOperation(binary.Right, fmt.Sprintf("regular expression %v", err))
+		return
+	}
+	v.regexps[pattern.Value] = re
+}
+
+type program struct {
+	translator   *translator
+	node         ast.Node
+	regexps      map[string]*regexp.Regexp
+	unified      map[ast.Node]internal.TranslationResult
+	dynamicTypes map[ast.Node]internal.ExprType
+}
+
+func (p *program) Evaluate(row any) (bool, error) {
+	values, err := rowValues(row)
+	if err != nil {
+		return false, err
+	}
+	result, err := (&evaluation{program: p, row: values}).eval(p.node)
+	if err != nil {
+		return false, err
+	}
+	return result.value == true, nil // NULL does not match
+}
+
+// rowValues returns a lookup of the row values by expr name
+func rowValues(row any) (func(name string) any, error) {
+	if m, ok := row.(map[string]any); ok {
+		return func(name string) any { return m[name] }, nil
+	}
+	rv := reflect.ValueOf(row)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	switch {
+	case rv.Kind() == reflect.Map && rv.Type().Key().Kind() == reflect.String:
+		return func(name string) any {
+			value := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+			if !value.IsValid() {
+				return nil
+			}
+			return value.Interface()
+		}, nil
+	case rv.Kind() == reflect.Struct:
+		fields := map[string]reflect.StructField{}
+		for _, field := range visibleFields(rv.Type(), "expr") {
+			if name, ok := tagName(field, "expr"); ok {
+				fields[name] = field
+			}
+		}
+		return func(name string) any {
+			field, ok := fields[name]
+			if !ok {
+				return nil
+			}
+			value, err := rv.FieldByIndexErr(field.Index)
+			if err != nil { // nil embedded struct pointer
+				return nil
+			}
+			return value.Interface()
+		}, nil
+	default:
+		return nil, fmt.Errorf("expected map or struct row, instead found %T", row)
+	}
+}
+
+type evaluation struct {
+	*program
+	row     func(name string) any
+	element *operand // array element referenced as # within a predicate
+}
+
+// operand is an evaluated expression, where the nil value is SQL NULL
+type operand struct {
+	value           any // int64, float64, bool, string, time.Time, time.Duration, []any for arrays, or decoded JSON
+	nilLiteral      bool
+	caseInsensitive bool
+	spec            JSONElement // spec of decoded JSON, whose array elements are cast to their type when accessed
+}
+
+func (e *evaluation) eval(node ast.Node) (operand, error) {
+	switch typed := node.(type) {
+	case *ast.NilNode:
+		return operand{nilLiteral: true}, nil
+	case *ast.IdentifierNode:
+		identifier, _ := e.translator.identifier(typed.Value)
+		value, err := columnValue(e.row(typed.Value), identifier.Type)
+		if err != nil {
+			return operand{}, fmt.Errorf("%v: %w", typed.Value, err)
+		}
+		result := operand{value: value, caseInsensitive: e.translator.caseInsensitive || identifier.CaseInsensitive}
+		if identifier.Type == IdentifierTypeJSON {
+			result.spec = identifier.JSONSpec
+		}
+		return result, nil
+	case *ast.StringNode:
+		if t, err := time.Parse(time.RFC3339Nano, typed.Value); err == nil {
+			return operand{value: t.UTC()}, nil
+		}
+		return operand{value: typed.Value}, nil
+	case *ast.IntegerNode:
+		return operand{value: int64(typed.Value)}, nil
+	case *ast.FloatNode:
+		return operand{value: typed.Value}, nil
+	case *ast.BoolNode:
+		return operand{value: typed.Value}, nil
+	case *ast.BinaryNode:
+		left, err := e.eval(typed.Left)
+		if err != nil {
+			return operand{}, err
+		}
+		switch { // skip the right operand if it cannot change the result
+		case (typed.Operator == "and" || typed.Operator == "&&") && left.value == false:
+			return operand{value: false}, nil
+		case (typed.Operator == "or" || typed.Operator == "||") && left.value == true:
+			return operand{value: true}, nil
+		case typed.Operator == "??" && left.value != nil:
+			return e.unify(typed, left), nil
+		}
+		right, err := e.eval(typed.Right)
+		if err != nil {
+			return operand{}, err
+		}
+		if typed.Operator == "??" {
+			return e.unify(typed, right), nil
+		}
+		value, err := e.evalBinary(typed.Operator, left, right)
+		return operand{value: value}, err
+	case *ast.UnaryNode:
+		if binary, ok := typed.Node.(*ast.BinaryNode); ok && typed.Operator == "not" && binary.Operator == "in" {
+			return e.eval(&ast.BinaryNode{Operator: "not in", Left: binary.Left, Right: binary.Right})
+		}
+		nested, err := e.eval(typed.Node)
+		if err != nil {
+			return operand{}, err
+		}
+		value, err := evalUnary(typed.Operator, nested.value)
+		return operand{value: value}, err
+	case *ast.ConditionalNode:
+		cond, err := e.eval(typed.Cond)
+		if err != nil {
+			return operand{}, err
+		}
+		branch := typed.Exp2 // NULL condition falls through to ELSE
+		if cond.value == true {
+			branch = typed.Exp1
+		}
+		result, err := e.eval(branch)
+		return e.unify(typed, result), err
+	case *ast.ArrayNode:
+		elems := make([]any, 0, len(typed.Nodes))
+		for _, elemNode := range typed.Nodes {
+			elem, err := e.eval(elemNode)
+			if err != nil {
+				return operand{}, err
+			}
+			elems = append(elems, elem.value)
+		}
+		return operand{value: elems}, nil
+	case *ast.PointerNode:
+		return *e.element, nil
+	case *ast.BuiltinNode, *ast.CallNode:
+		if builtin, ok := node.(*ast.BuiltinNode); ok && predicates[builtin.Name] != "" {
+			return e.evalPredicate(builtin)
+		}
+		if call, ok := node.(*ast.CallNode); ok {
+			if method, ok := call.Callee.(*ast.MemberNode); ok {
+				return e.evalDatePart(method)
+			}
+		}
+		name, argNodes, _ := functionCall(node)
+		if _, custom := e.translator.functions[name]; castTypes[name] != "" && !custom {
+			return e.eval(argNodes[0]) // dynamic JSON values are cast by evalJSON
+		}
+		args := make([]any, 0, len(argNodes))
+		for _, argNode := range argNodes {
+			if _, custom := e.translator.functions[name]; name == "date" && !custom {
+				if str, ok := argNode.(*ast.StringNode); ok { // parsed by date rather than truncated, as translated
+					args = append(args, str.Value)
+					continue
+				}
+			}
+			arg, err := e.eval(argNode)
+			if err != nil {
+				return operand{}, err
+			}
+			args = append(args, arg.value)
+		}
+		value, err := e.evalFunction(name, args)
+		if err != nil {
+			return operand{}, fmt.Errorf("%v: %w", node, err)
+		}
+		return operand{value: value}, nil
+	case *ast.MemberNode:
+		return e.evalJSON(typed)
+	}
+	return operand{}, unsupportedOperation(node, node.String())
+}
+
+// functionCall returns the name and the arguments of builtin and custom function calls
+func functionCall(node ast.Node) (string, []ast.Node, bool) {
+	switch typed := node.(type) {
+	case *ast.BuiltinNode:
+		return typed.Name, typed.Arguments, true
+	case *ast.CallNode:
+		if callee, ok := typed.Callee.(*ast.IdentifierNode); ok {
+			return callee.Value, typed.Arguments, true
+		}
+	}
+	return "", nil, false
+}
+
+// evalFunction calls the custom function, or the builtin function, which like its SQL equivalent returns NULL
+// for NULL arguments
+func (e *evaluation) evalFunction(name string, args []any) (any, error) {
+	if fn, ok := e.translator.functions[name]; ok {
+		for i, arg := range args {
+			var err error
+			if args[i], err = columnValue(arg, fn.Args[i]); err != nil { // integers passed as float arguments
+				return nil, err
+			}
+		}
+		result, err := fn.Eval(args...)
+		if err != nil {
+			return nil, err
+		}
+		return columnValue(result, fn.Result)
+	}
+	if slices.Contains(args, nil) {
+		return nil, nil
+	}
+	switch name {
+	case "len":
+		if elems, ok := args[0].([]any); ok {
+			return int64(len(elems)), nil
+		}
+		return int64(utf8.RuneCountInString(args[0].(string))), nil
+	case "lower":
+		return strings.ToLower(args[0].(string)), nil
+	case "upper":
+		return strings.ToUpper(args[0].(string)), nil
+	case "trim":
+		return strings.Trim(args[0].(string), " "), nil
+	case "abs", "ceil", "floor", "round":
+		if i, ok := args[0].(int64); ok {
+			if name == "abs" && i < 0 {
+				return -i, nil
+			}
+			return i, nil
+		}
+		return map[string]func(float64) float64{
+			"abs":   math.Abs,
+			"ceil":  math.Ceil,
+			"floor": math.Floor,
+			"round": math.RoundToEven, // round of double precision
+		}[name](args[0].(float64)), nil
+	case "now":
+		if e.translator.clock != nil {
+			return e.translator.clock().UTC(), nil
+		}
+		return time.Now().UTC(), nil
+	case "date":
+		switch arg := args[0].(type) {
+		case time.Time:
+			return internal.TruncateDay(arg), nil
+		case string:
+			t, err := internal.ParseDate(arg)
+			return t.UTC(), err
+		}
+	case "duration":
+		return time.ParseDuration(args[0].(string))
+	}
+	return nil, fmt.Errorf("function %v cannot be evaluated in memory", name)
+}
+
+// evalPredicate evaluates the closure for each element of the array, where elements for which it is NULL
+// do not match, like rows of the translated subquery
+func (e *evaluation) evalPredicate(node *ast.BuiltinNode) (operand, error) {
+	array, err := e.eval(node.Arguments[0])
+	if err != nil {
+		return operand{}, err
+	}
+	elems, _ := array.value.([]any) // NULL arrays have no elements
+	arraySpec, isJSON := array.spec.(jsonArray)
+	matching := []any{}
+	for _, elem := range elems {
+		scoped := *e
+		scoped.element = &operand{value: elem, caseInsensitive: array.caseInsensitive}
+		if isJSON {
+			scoped.element.spec = arraySpec.element
+			if scoped.element.value, err = jsonValue(elem, arraySpec.element); err != nil {
+				return operand{}, fmt.Errorf("%v: %w", node.Arguments[0], err)
+			}
+		}
+		condition, err := scoped.eval(node.Arguments[1].(*ast.ClosureNode).Node)
+		if err != nil {
+			return operand{}, err
+		}
+		if condition.value == true {
+			matching = append(matching, elem)
+		}
+	}
+	switch node.Name {
+	case "any":
+		return operand{value: len(matching) > 0}, nil
+	case "all":
+		return operand{value: len(matching) == len(elems)}, nil
+	case "none":
+		return operand{value: len(matching) == 0}, nil
+	case "one":
+		return operand{value: len(matching) == 1}, nil
+	default:
+		return operand{value: matching, caseInsensitive: array.caseInsensitive, spec: array.spec}, nil
+	}
+}
+
+// evalDatePart evaluates the date part accessor of the timestamp in UTC
+func (e *evaluation) evalDatePart(method *ast.MemberNode) (operand, error) {
+	receiver, err := e.eval(method.Node)
+	if err != nil || receiver.value == nil {
+		return operand{}, err
+	}
+	t := receiver.value.(time.Time).UTC()
+	var part int
+	switch method.Property.(*ast.StringNode).Value {
+	case "Year":
+		part = t.Year()
+	case "Month":
+		part = int(t.Month())
+	case "Day":
+		part = t.Day()
+	case "Hour":
+		part = t.Hour()
+	case "Minute":
+		part = t.Minute()
+	case "Second":
+		part = t.Second()
+	case "Weekday":
+		part = int(t.Weekday())
+	case "YearDay":
+		part = t.YearDay()
+	}
+	return operand{value: int64(part)}, nil
+}
+
+// unify converts the result of a conditional or nil-coalescing expression to the type unified from all of its operands
+func (e *evaluation) unify(node ast.Node, result operand) operand {
+	unified := e.unified[node]
+	if i, ok := result.value.(int64); ok && (unified.Type == internal.ExprTypeFloat || unified.Type == internal.ExprTypeFloatIdentifier) {
+		result.value = float64(i)
+	}
+	return operand{value: result.value, caseInsensitive: unified.CaseInsensitive}
+}
+
+// evalJSON extracts the JSON element like the -> and ->> operators, casting scalar elements to their type
+func (e *evaluation) evalJSON(node *ast.MemberNode) (operand, error) {
+	parent, err := e.eval(node.Node)
+	if err != nil {
+		return operand{}, err
+	}
+	var document any // missing properties and elements, and those of values of other types, are NULL
+	var element JSONElement
+	switch property := node.Property.(type) {
+	case *ast.StringNode:
+		switch spec := parent.spec.(type) {
+		case JSONTree:
+			element = spec[property.Value]
+		case jsonDynamic:
+			element = spec
+			if dynamicType, ok := e.dynamicTypes[node]; ok {
+				element = JSONLeaf(dynamicType)
+			}
+		}
+		object, _ := parent.value.(map[string]any)
+		document = object[property.Value]
+	case *ast.IntegerNode:
+		element = parent.spec.(jsonArray).element
+		if elems, _ := parent.value.([]any); property.Value < len(elems) {
+			document = elems[property.Value]
+		}
+	}
+	value, err := jsonValue(document, element)
+	if err != nil {
+		return operand{}, fmt.Errorf("%v: %w", node, err)
+	}
+	return operand{value: value, caseInsensitive: parent.caseInsensitive, spec: element}, nil
+}
+
+// jsonValue casts scalar JSON elements to their type, keeping objects and arrays decoded
+func jsonValue(document any, element JSONElement) (any, error) {
+	switch leaf := element.(type) {
+	case JSONLeaf:
+		return jsonLeafValue(document, IdentifierType(leaf))
+	case jsonTimestamp:
+		return jsonTimestampValue(document, leaf.format)
+	}
+	return document, nil
+}
+
+// jsonTimestampValue converts the JSON element stored in the format to a timestamp
+func jsonTimestampValue(element any, format JSONTimestampFormat) (any, error) {
+	if format == JSONTimestampFormatRFC3339 {
+		return jsonLeafValue(element, IdentifierTypeTimestamp)
+	}
+	value, err := jsonLeafValue(element, IdentifierTypeFloat)
+	if value == nil || err != nil {
+		return nil, err
+	}
+	seconds := value.(float64)
+	if format == JSONTimestampFormatEpochMillis {
+		seconds /= 1000
+	}
+	return time.UnixMicro(int64(math.Round(seconds * 1e6))).UTC(), nil
+}
+
+func (e *evaluation) evalBinary(op string, left, right operand) (any, error) {
+	switch op {
+	case "and", "&&":
+		return and(left.value, right.value), nil
+	case "or", "||":
+		return or(left.value, right.value), nil
+	case "==", "!=":
+		if left.nilLiteral || right.nilLiteral {
+			return (left.value == nil && right.value == nil) == (op == "=="), nil
+		}
+		if left.value == nil || right.value == nil {
+			return nil, nil
+		}
+		l, r := left.value, right.value
+		if left.caseInsensitive || right.caseInsensitive {
+			l, r = lowerString(l), lowerString(r)
+		}
+		c, err := compare(l, r)
+		return (c == 0) == (op == "=="), err
+	case "<", ">", "<=", ">=":
+		if left.value == nil || right.value == nil {
+			return nil, nil
+		}
+		c, err := compare(left.value, right.value)
+		switch op {
+		case "<":
+			return c < 0, err
+		case ">":
+			return c > 0, err
+		case "<=":
+			return c <= 0, err
+		default:
+			return c >= 0, err
+		}
+	case "in", "not in":
+		if right.value == nil { // NULL array column
+			return nil, nil
+		}
+		if arraySpec, ok := right.spec.(jsonArray); ok {
+			contains, err := jsonContains(right.value, arraySpec.element, left.value)
+			return contains == (op == "in"), err
+		}
+		elems := right.value.([]any)
+		if len(elems) == 0 {
+			return op == "not in", nil
+		}
+		if left.value == nil {
+			return nil, nil
+		}
+		hasNull := false
+		for _, elem := range elems {
+			if elem == nil { // NULL elements of array columns
+				hasNull = true
+				continue
+			}
+			if c, err := compare(left.value, elem); err != nil || c == 0 {
+				return op == "in", err
+			}
+		}
+		if hasNull {
+			return nil, nil
+		}
+		return op == "not in", nil
+	case "contains", "startsWith", "endsWith":
+		if left.value == nil || right.value == nil {
+			return nil, nil
+		}
+		s, pattern := left.value.(string), right.value.(string)
+		if left.caseInsensitive || right.caseInsensitive {
+			s, pattern = strings.ToLower(s), strings.ToLower(pattern)
+		}
+		switch op {
+		case "contains":
+			return strings.Contains(s, pattern), nil
+		case "startsWith":
+			return strings.HasPrefix(s, pattern), nil
+		default:
+			return strings.HasSuffix(s, pattern), nil
+		}
+	case "matches":
+		if left.value == nil || right.value == nil {
+			return nil, nil
+		}
+		return e.regexps[right.value.(string)].MatchString(left.value.(string)), nil
+	default:
+		return arithmetic(op, left.value, right.value)
+	}
+}
+
+// jsonContains checks containment of the value in the JSON array like the @> operator, for which
+// NULL is the JSON null element
+func jsonContains(array any, element JSONElement, value any) (bool, error) {
+	elems, _ := array.([]any)
+	for _, elem := range elems {
+		elem, err := jsonValue(elem, element)
+		if err != nil {
+			return false, err
+		}
+		if value == nil || elem == nil {
+			if value == elem {
+				return true, nil
+			}
+			continue
+		}
+		c, err := compare(value, elem)
+		if err != nil {
+			return false, err
+		}
+		if c == 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func evalUnary(op string, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch op {
+	case "not", "!":
+		return !value.(bool), nil
+	case "-":
+		if i, ok := value.(int64); ok {
+			if i == math.MinInt64 {
+				return nil, ErrOutOfRange
+			}
+			return -i, nil
+		}
+		return -value.(float64), nil
+	default:
+		return nil, fmt.Errorf("unsupported operator %v", op)
+	}
+}
+
+// and implements the three-valued logic conjunction
+func and(left, right any) any {
+	switch {
+	case left == false || right == false:
+		return false
+	case left == nil || right == nil:
+		return nil
+	default:
+		return true
+	}
+}
+
+// or implements the three-valued logic disjunction
+func or(left, right any) any {
+	switch {
+	case left == true || right == true:
+		return true
+	case left == nil || right == nil:
+		return nil
+	default:
+		return false
+	}
+}
+
+// arithmetic keeps integer results for integer operands, except for exponentiation, and fails for results
+// overflowing 64 bits
+func arithmetic(op string, left, right any) (any, error) {
+	if left == nil || right == nil {
+		return nil, nil
+	}
+	if result, ok := temporalArithmetic(op, left, right); ok {
+		return result, nil
+	}
+	l, lIsInt := left.(int64)
+	r, rIsInt := right.(int64)
+	if lIsInt && rIsInt && op != "**" && op != "^" {
+		return intArithmetic(op, l, r)
+	}
+	lf, rf := toFloat(left), toFloat(right)
+	var result float64
+	switch op {
+	case "+":
+		result = lf + rf
+	case "-":
+		result = lf - rf
+	case "*":
+		result = lf * rf
+	case "/", "%":
+		if rf == 0 {
+			return nil, ErrDivisionByZero
+		}
+		if op == "/" {
+			result = lf / rf
+		} else {
+			result = math.Mod(lf, rf)
+		}
+	case "**", "^":
+		result = math.Pow(lf, rf)
+	default:
+		return nil, fmt.Errorf("unsupported operator %v", op)
+	}
+	if math.IsInf(result, 0) && !math.IsInf(lf, 0) && !math.IsInf(rf, 0) {
+		return nil, ErrOutOfRange
+	}
+	return result, nil
+}
+
+// intArithmetic computes the operator of integers exactly, failing for results which are not 64-bit integers
+func intArithmetic(op string, left, right int64) (any, error) {
+	var result *big.Int
+	switch op {
+	case "+":
+		result = new(big.Int).Add(big.NewInt(left), big.NewInt(right))
+	case "-":
+		result = new(big.Int).Sub(big.NewInt(left), big.NewInt(right))
+	case "*":
+		result = new(big.Int).Mul(big.NewInt(left), big.NewInt(right))
+	case "/", "%":
+		if right == 0 {
+			return nil, ErrDivisionByZero
+		}
+		if op == "/" {
+			result = new(big.Int).Quo(big.NewInt(left), big.NewInt(right))
+		} else {
+			result = new(big.Int).Rem(big.NewInt(left), big.NewInt(right))
+		}
+	default:
+		return nil, fmt.Errorf("unsupported operator %v", op)
+	}
+	if !result.IsInt64() {
+		return nil, ErrOutOfRange
+	}
+	return result.Int64(), nil
+}
+
+// temporalArithmetic adds and subtracts timestamps and durations
+func temporalArithmetic(op string, left, right any) (any, bool) {
+	lt, lIsTime := left.(time.Time)
+	rt, rIsTime := right.(time.Time)
+	ld, lIsDuration := left.(time.Duration)
+	rd, rIsDuration := right.(time.Duration)
+	if op == "-" {
+		rd = -rd
+	}
+	switch {
+	case lIsTime && rIsTime && op == "-":
+		return lt.Sub(rt), true
+	case lIsTime && rIsDuration:
+		return lt.Add(rd), true
+	case lIsDuration && rIsTime && op == "+":
+		return rt.Add(ld), true
+	case lIsDuration && rIsDuration:
+		return ld + rd, true
+	default:
+		return nil, false
+	}
+}
+
+func toFloat(value any) float64 {
+	if i, ok := value.(int64); ok {
+		return float64(i)
+	}
+	return value.(float64)
+}
+
+func compare(left, right any) (int, error) {
+	switch l := left.(type) {
+	case int64:
+		switch r := right.(type) {
+		case int64:
+			return cmp.Compare(l, r), nil
+		case float64:
+			return cmp.Compare(float64(l), r), nil
+		}
+	case float64:
+		switch r := right.(type) {
+		case int64:
+			return cmp.Compare(l, float64(r)), nil
+		case float64:
+			return cmp.Compare(l, r), nil
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return strings.Compare(l, r), nil
+		}
+	case bool:
+		if r, ok := right.(bool); ok {
+			switch { // false < true
+			case l == r:
+				return 0, nil
+			case r:
+				return -1, nil
+			default:
+				return 1, nil
+			}
+		}
+	case time.Time:
+		if r, ok := right.(time.Time); ok {
+			return l.Compare(r), nil
+		}
+	case time.Duration:
+		if r, ok := right.(time.Duration); ok {
+			return cmp.Compare(l, r), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %T with %T", left, right)
+}
+
+func lowerString(value any) any {
+	if s, ok := value.(string); ok {
+		return strings.ToLower(s)
+	}
+	return value
+}
+
+// columnValue converts the Go value of a column to the value of its type, where nil values are NULL
+func columnValue(value any, identifierType IdentifierType) (any, error) {
+	if elemType, ok := internal.ElemType(internal.ExprType(identifierType)); ok {
+		return arrayValue(value, IdentifierType(elemType)) // before driver.Valuer, which formats array literals
+	}
+	if valuer, ok := value.(driver.Valuer); ok {
+		var err error
+		if value, err = valuer.Value(); err != nil {
+			return nil, err
+		}
+	}
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, nil
+	}
+	number, isNumber := rv.Interface().(json.Number)
+	switch kind := rv.Kind(); identifierType {
+	case IdentifierTypeInt:
+		switch {
+		case rv.CanInt():
+			return rv.Int(), nil
+		case rv.CanUint():
+			return int64(rv.Uint()), nil
+		case rv.CanFloat() && rv.Float() == math.Trunc(rv.Float()): // numbers decoded from JSON
+			return int64(rv.Float()), nil
+		case isNumber:
+			return number.Int64()
+		}
+	case IdentifierTypeFloat:
+		switch {
+		case rv.CanInt():
+			return float64(rv.Int()), nil
+		case rv.CanUint():
+			return float64(rv.Uint()), nil
+		case rv.CanFloat():
+			return rv.Float(), nil
+		case isNumber:
+			return number.Float64()
+		}
+	case IdentifierTypeBool:
+		if kind == reflect.Bool {
+			return rv.Bool(), nil
+		}
+	case IdentifierTypeString:
+		if kind == reflect.String {
+			return rv.String(), nil
+		}
+	case IdentifierTypeTimestamp:
+		if t, ok := rv.Interface().(time.Time); ok {
+			return t, nil
+		}
+		if kind == reflect.String {
+			return time.Parse(time.RFC3339Nano, rv.String())
+		}
+	case IdentifierTypeJSON:
+		return jsonDocument(rv.Interface())
+	}
+	return nil, fmt.Errorf("cannot convert %T to %v", value, identifierType)
+}
+
+// arrayValue converts slices, or JSON arrays as stored by MySQL and SQLite, to []any of the element type
+func arrayValue(value any, elemType IdentifierType) (any, error) {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Pointer || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil, nil
+	}
+	var data []byte
+	switch {
+	case rv.Kind() == reflect.String:
+		data = []byte(rv.String())
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		data = rv.Bytes()
+	}
+	if data != nil {
+		document, err := jsonDocument(data)
+		if err != nil || document == nil {
+			return nil, err
+		}
+		rv = reflect.ValueOf(document)
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot convert %T to array", value)
+	}
+	elems := make([]any, 0, rv.Len())
+	for i := range rv.Len() {
+		elem, err := columnValue(rv.Index(i).Interface(), elemType)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, elem)
+	}
+	return elems, nil
+}
+
+// jsonDocument decodes the JSON column value, given either as encoded JSON or as a value to be encoded
+func jsonDocument(value any) (any, error) {
+	var data []byte
+	switch v := value.(type) {
+	case json.RawMessage:
+		data = v
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		var err error
+		if data, err = json.Marshal(v); err != nil {
+			return nil, err
+		}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document any
+	if err := decoder.Decode(&document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+// jsonLeafValue casts the text of the JSON element to its type, as done in the translated condition
+func jsonLeafValue(element any, identifierType IdentifierType) (any, error) {
+	if element == nil || identifierType == IdentifierTypeJSON {
+		return element, nil
+	}
+	var text string
+	switch v := element.(type) {
+	case string:
+		text = v
+	case json.Number:
+		text = v.String()
+	case bool:
+		text = strconv.FormatBool(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		text = string(data)
+	}
+	var value any
+	var err error
+	switch identifierType {
+	case IdentifierTypeInt:
+		value, err = strconv.ParseInt(text, 10, 64)
+	case IdentifierTypeFloat:
+		value, err = strconv.ParseFloat(text, 64)
+	case IdentifierTypeBool:
+		value, err = strconv.ParseBool(text)
+	case IdentifierTypeTimestamp:
+		value, err = time.Parse(time.RFC3339Nano, text)
+	default:
+		value = text
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert %q to %v", text, identifierType)
+	}
+	return value, nil
+}
@@ -0,0 +1,244 @@
+package filter_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/happening-oss/expr2sql/pkg/filter"
+)
+
+var _ = Describe("Evaluator", func() {
+	var evaluator filter.Evaluator
+
+	BeforeEach(func() {
+		evaluator = filter.NewEvaluator([]filter.Identifier{
+			{ExprName: "intField", Type: filter.IdentifierTypeInt},
+			{ExprName: "floatField", Type: filter.IdentifierTypeFloat},
+			{ExprName: "boolField", Type: filter.IdentifierTypeBool},
+			{ExprName: "stringField", Type: filter.IdentifierTypeString},
+			{ExprName: "ciField", Type: filter.IdentifierTypeString, CaseInsensitive: true},
+			{ExprName: "tsField", Type: filter.IdentifierTypeTimestamp},
+			{ExprName: "tags", Type: filter.IdentifierTypeArray(filter.IdentifierTypeString)},
+			{ExprName: "scores", Type: filter.IdentifierTypeArray(filter.IdentifierTypeInt)},
+			{ExprName: "attrs", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONDynamic(nil)},
+			{ExprName: "jsonField", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+				"nested": filter.JSONTree{
+					"stringProperty": filter.JSONLeaf(filter.IdentifierTypeString),
+				},
+				"intProperty":  filter.JSONLeaf(filter.IdentifierTypeInt),
+				"boolProperty": filter.JSONLeaf(filter.IdentifierTypeBool),
+				"tsProperty":   filter.JSONLeaf(filter.IdentifierTypeTimestamp),
+				"sentAt":       filter.JSONTimestamp(filter.JSONTimestampFormatEpochSeconds),
+				"seenAt":       filter.JSONTimestamp(filter.JSONTimestampFormatEpochMillis),
+				"createdAt":    filter.JSONLeaf(filter.IdentifierTypeTimestamp),
+				"items": filter.JSONArray(filter.JSONTree{
+					"sku": filter.JSONLeaf(filter.IdentifierTypeString),
+					"qty": filter.JSONLeaf(filter.IdentifierTypeInt),
+				}),
+				"tags": filter.JSONArray(filter.JSONLeaf(filter.IdentifierTypeString)),
+			}},
+		}, filter.WithFunction("double", filter.Function{
+			SQL:    "{0} * 2",
+			Args:   []filter.IdentifierType{filter.IdentifierTypeFloat},
+			Result: filter.IdentifierTypeFloat,
+			Eval: func(args ...any) (any, error) {
+				if args[0] == nil {
+					return nil, nil
+				}
+				return args[0].(float64) * 2, nil
+			},
+		}))
+	})
+
+	row := map[string]any{
+		"intField":    7,
+		"floatField":  2.5,
+		"boolField":   true,
+		"stringField": "50% off_sale",
+		"ciField":     "ACME Corp",
+		"tsField":     time.Date(2024, 9, 17, 10, 0, 0, 0, time.UTC),
+		"tags":        []string{"vip", "eu-west"},
+		"scores":      `[40, 75, 90]`,
+		"attrs":       map[string]any{"size": "5", "color": "red", "ratio": 0.25, "dims": map[string]any{"flat": true}},
+		"jsonField": json.RawMessage(`{"nested": {"stringProperty": "abcd"}, "intProperty": "42", "boolProperty": true,
+			"sentAt": 1726560000, "seenAt": 1726560000500, "createdAt": "2024-09-17T11:00:00+03:00",
+			"items": [{"sku": "A1", "qty": 2}, {"sku": "B2", "qty": "5"}], "tags": ["gift", null]}`),
+	}
+
+	DescribeTable("evaluates expressions",
+		func(query string, expected bool) {
+			matches, err := evaluator.Evaluate(query, row)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(matches).To(Equal(expected))
+		},
+		Entry("comparison", `intField >= 7 and floatField < 3.0`, true),
+		Entry("integer division", `intField / 2 == 3`, true),
+		Entry("mixed arithmetic", `floatField * 2 == 5.0`, true),
+		Entry("mixed numeric identifiers", `floatField < intField and intField != floatField`, true),
+		Entry("exponent", `intField ** 2 == 49`, true),
+		Entry("timestamp", `tsField > "2024-09-17T09:00:00Z" and tsField < "2024-09-17T12:00:00+02:00"`, false),
+		Entry("unary", `!boolField or intField - 8 == -1`, true),
+		Entry("literal wildcards", `stringField contains "0% off_" and not (stringField contains "0%_off")`, true),
+		Entry("prefix and suffix", `stringField startsWith "50" and stringField endsWith "sale"`, true),
+		Entry("regex", `stringField matches "^[0-9]+%"`, true),
+		Entry("case-sensitive strings", `stringField == "50% OFF_SALE"`, false),
+		Entry("case-insensitive strings", `ciField == "acme corp" and ciField contains "CORP"`, true),
+		Entry("string functions", `upper(stringField) == "50% OFF_SALE" and len(trim(stringField)) == 12`, true),
+		Entry("numeric functions", `round(floatField) == 2.0 and ceil(floatField) == 3.0 and abs(intField - 10) == 3`, true),
+		Entry("date functions", `tsField > date("2024-09-17") and tsField < now()`, true),
+		Entry("timestamp arithmetic", `tsField - date("2024-09-17") == duration("10h") and tsField + duration("-1h") < date("2024-09-17 09:30:00")`, true),
+		Entry("dates of timestamps", `date(tsField) == date("2024-09-17") and date("2024-09-17T10:00:00Z") == tsField`, true),
+		Entry("relative time", `tsField > now() - duration("87600h")`, true),
+		Entry("date part accessors", `tsField.Year() == 2024 and tsField.Month() == 9 and tsField.Weekday() == 2 and tsField.Hour() + 1 == 11`, true),
+		Entry("custom functions", `double(floatField) == 5.0 and double(nil ?? 1) == 2.0`, true),
+		Entry("membership", `intField in [1, 7] and stringField not in ["abcd"]`, true),
+		Entry("mixed numeric membership", `floatField in [1, 2.5] and intField in [7.0] and intField not in [7.5]`, true),
+		Entry("empty membership", `intField in [] or not (intField not in [])`, false),
+		Entry("array membership", `"vip" in tags and 40 in scores and "VIP" not in tags`, true),
+		Entry("array predicates", `any(tags, # startsWith "eu-") and all(scores, # >= intField) and none(scores, # > 100) and one(scores, # < 50)`, true),
+		Entry("array filter", `len(filter(scores, # > 50)) == 2 and len(tags) == 2`, true),
+		Entry("nested array predicates", `any(tags, # == "vip" and all(scores, # > 30)) and none(tags, any(scores, # < 0))`, true),
+		Entry("json properties", `jsonField.nested.stringProperty == "abcd" and jsonField.intProperty > 40 and jsonField.boolProperty`, true),
+		Entry("json array elements", `jsonField.items[1].qty == 5 and jsonField.items[0].sku == "A1" and jsonField.items[2].sku == nil`, true),
+		Entry("json array predicates", `any(jsonField.items, .qty > 3) and all(jsonField.items, .sku matches "^[A-Z][0-9]$")`, true),
+		Entry("json array membership", `"gift" in jsonField.tags and "box" not in jsonField.tags`, true),
+		Entry("json array filter", `len(filter(jsonField.items, .qty < 3)) == 1 and len(jsonField.tags) == 2`, true),
+		Entry("dynamic json properties", `attrs.size > 3 and attrs.color in ["red"] and attrs.ratio < 0.5 and attrs.missing == nil`, true),
+		Entry("dynamic json numbers compared with integers", `attrs.ratio > 0 and attrs.ratio < 1 and attrs.size in [5]`, true),
+		Entry("dynamic json casts", `bool(attrs.dims.flat) and float(attrs.size) / 2 == 2.5 and (attrs.weight ?? 1) == 1`, true),
+		Entry("missing json property", `jsonField.tsProperty == nil`, true),
+		Entry("json timestamps", `jsonField.sentAt == jsonField.createdAt and jsonField.seenAt > jsonField.sentAt and jsonField.seenAt < "2024-09-17T08:00:01Z"`, true),
+		Entry("ternary", `(intField > 5 ? floatField : 0) == 2.5`, true),
+		Entry("ternary unified to float", `(boolField ? intField : 0.5) / 2 == 3.5`, true),
+		Entry("ternary unified within predicate", `any(scores, (# > 80 ? # : 0.5) / 4 == 22.5)`, true),
+		Entry("nil-coalescing", `(jsonField.tsProperty ?? tsField) == tsField and (ciField ?? "x") == "acme corp"`, true),
+	)
+
+	DescribeTable("evaluates NULL with three-valued logic",
+		func(query string, expected bool) {
+			matches, err := evaluator.Evaluate(query, map[string]any{"intField": nil, "boolField": true})
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(matches).To(Equal(expected))
+		},
+		Entry("comparison", `intField == 1 or intField != 1`, false),
+		Entry("nil comparison", `intField == nil and stringField == nil`, true),
+		Entry("nil on the left side", `nil == intField and nil != boolField`, true),
+		Entry("negated comparison", `not (intField > 1)`, false),
+		Entry("arithmetic", `intField + 1 > 0`, false),
+		Entry("disjunction with true", `intField == 1 or boolField`, true),
+		Entry("conjunction with false", `not (intField == 1 and !boolField)`, true),
+		Entry("membership", `intField not in [1, 2]`, false),
+		Entry("array membership", `"vip" in tags or not ("vip" in tags)`, false),
+		Entry("array predicates", `any(tags, # == "vip") or all(scores, # > 1)`, true),
+		Entry("string operators", `stringField contains "a" or not (stringField startsWith "a")`, false),
+		Entry("json column", `jsonField.intProperty == nil`, true),
+		Entry("json array membership", `"gift" in jsonField.tags or not ("gift" in jsonField.tags)`, false),
+		Entry("ternary with NULL condition", `(intField > 1 ? false : true)`, true),
+		Entry("nil-coalescing", `(intField ?? 2) == 2`, true),
+	)
+
+	It("evaluates structs by expr tags", func() {
+		type model struct {
+			ID       int64          `expr:"intField"`
+			Name     sql.NullString `expr:"stringField"`
+			Settings map[string]any `expr:"jsonField"`
+		}
+		program, err := evaluator.Compile(`intField == 1 and stringField == nil and jsonField.nested.stringProperty startsWith "ab"`)
+		Expect(err).ToNot(HaveOccurred())
+
+		matches, err := program.Evaluate(&model{ID: 1, Settings: map[string]any{"nested": map[string]any{"stringProperty": "abcd"}}})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(matches).To(BeTrue())
+	})
+
+	It("evaluates now with the clock", func() {
+		evaluator = filter.NewEvaluator([]filter.Identifier{
+			{ExprName: "tsField", Type: filter.IdentifierTypeTimestamp},
+		}, filter.WithClock(func() time.Time {
+			return time.Date(2024, 9, 18, 9, 0, 0, 0, time.UTC)
+		}))
+
+		matches, err := evaluator.Evaluate(`tsField > now() - duration("24h")`, row)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(matches).To(BeTrue())
+	})
+
+	It("fails with the translation errors", func() {
+		_, err := evaluator.Compile(`intField == "abcd"`)
+
+		Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+	})
+
+	It("fails for invalid regex", func() {
+		_, err := evaluator.Compile(`stringField matches "[a-"`)
+
+		Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+	})
+
+	It("fails for conditional regex", func() {
+		_, err := evaluator.Compile(`stringField matches (intField > 0 ? "a" : "b")`)
+
+		Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+	})
+
+	It("fails for custom functions without evaluation", func() {
+		evaluator = filter.NewEvaluator([]filter.Identifier{
+			{ExprName: "intField", Type: filter.IdentifierTypeInt},
+		}, filter.WithFunction("double", filter.Function{
+			SQL:    "{0} * 2",
+			Args:   []filter.IdentifierType{filter.IdentifierTypeInt},
+			Result: filter.IdentifierTypeInt,
+		}))
+
+		_, err := evaluator.Compile(`double(intField) > 1`)
+
+		Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+	})
+
+	It("fails for relations", func() {
+		evaluator = filter.NewEvaluator([]filter.Identifier{
+			{ExprName: "customer", DBName: "orders.customer_id", Type: filter.IdentifierTypeRelation, Relation: &filter.Relation{
+				Table: "customers", Column: "id", Identifiers: []filter.Identifier{{ExprName: "country", Type: filter.IdentifierTypeString}},
+			}},
+		})
+
+		_, err := evaluator.Compile(`customer.country == "DE"`)
+
+		Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+	})
+
+	It("fails for division by zero", func() {
+		_, err := evaluator.Evaluate(`intField / 0 == 1`, row)
+
+		Expect(err).To(MatchError(filter.ErrDivisionByZero))
+	})
+
+	It("fails for overflowing arithmetic", func() {
+		for _, query := range []string{
+			`intField + 9223372036854775807 > 0`,
+			`intField - 9223372036854775807 - 10 < 0`,
+			`intField * 4611686018427387904 > 0`,
+			`-(intField - 7 - 9223372036854775807 - 1) > 0`,
+			`10 ** 400 > floatField`,
+			`floatField * 1e308 * 10 > 0`,
+		} {
+			_, err := evaluator.Evaluate(query, row)
+
+			Expect(err).To(MatchError(filter.ErrOutOfRange), query)
+		}
+	})
+
+	It("fails for invalid json property values", func() {
+		_, err := evaluator.Evaluate(`jsonField.intProperty == 1`, map[string]any{"jsonField": `{"intProperty": "abcd"}`})
+
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,52 @@
+package filter
+
+import (
+	"github.com/happening-oss/expr2sql/pkg/filter/internal"
+)
+
+// Function is a custom SQL function which can be called in expressions, e.g. distance(lat, lng, 40.7, -74.0)
+type Function struct {
+	// SQL is the template of the call, with arguments referenced as {0}, {1}, ...
+	// e.g. "ST_Distance(ST_MakePoint({1}, {0}), ST_MakePoint({3}, {2}))"
+	SQL string
+	// Args are the types of the arguments, accepting both identifiers and literals of the type,
+	// where float arguments accept integers too
+	Args   []IdentifierType
+	Result IdentifierType
+	// Eval implements the function for the Evaluator, with nil for NULL arguments and int64, float64, bool,
+	// string or time.Time otherwise. Evaluation of queries calling functions without it fails.
+	Eval func(args ...any) (any, error)
+}
+
+// WithFunction registers the function under the name, taking precedence over the builtin function of the same name
+func WithFunction(name string, fn Function) TranslatorOption {
+	return func(t *translator) {
+		if t.functions == nil {
+			t.functions = map[string]Function{}
+		}
+		t.functions[name] = fn
+	}
+}
+
+func (fn Function) descriptor() internal.FunctionDescriptor {
+	argTypes := make([][]internal.ExprType, 0, len(fn.Args))
+	for _, arg := range fn.Args {
+		accepted := []internal.ExprType{internal.ExprType(arg)}
+		if literalType, ok := internal.LiteralType(internal.ExprType(arg)); ok {
+			accepted = append(accepted, literalType)
+		}
+		if arg == IdentifierTypeFloat {
+			accepted = append(accepted, internal.ExprTypeIntIdentifier, internal.ExprTypeInt)
+		}
+		argTypes = append(argTypes, accepted)
+	}
+	descriptor := internal.TemplateFunctionDescriptor(fn.SQL, argTypes, internal.ExprType(fn.Result))
+	fnTranslator := descriptor.FnTranslator
+	descriptor.FnTranslator = func(args []internal.TranslationResult) (internal.TranslationResult, error) {
+		for i, arg := range args {
+			args[i] = parenthesize(arg, precedenceUnary) // the template may apply operators to the arguments
+		}
+		return fnTranslator(args)
+	}
+	return descriptor
+}
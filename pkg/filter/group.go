@@ -0,0 +1,151 @@
+package filter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/builtin"
+	"github.com/expr-lang/expr/conf"
+	"github.com/expr-lang/expr/parser"
+
+	"github.com/happening-oss/expr2sql/pkg/filter/internal"
+)
+
+type SQLHavingCondition string
+
+type SQLGroupBy string
+
+var numericIdentifierTypes = []internal.ExprType{internal.ExprTypeIntIdentifier, internal.ExprTypeFloatIdentifier}
+
+// aggregateFunctions can be called by HAVING conditions, where count() counts all rows of the group.
+// Booleans have no minimum or maximum, as Postgres has no such aggregates.
+var aggregateFunctions = map[string]internal.FunctionDescriptor{
+	"count": internal.TemplateFunctionDescriptor("count({0})", [][]internal.ExprType{internal.ScalarIdentifierTypes}, internal.ExprTypeIntIdentifier),
+	"sum":   internal.AggregateFunctionDescriptor("sum({0})", numericIdentifierTypes),
+	"avg":   internal.TemplateFunctionDescriptor("avg({0})", [][]internal.ExprType{numericIdentifierTypes}, internal.ExprTypeFloatIdentifier),
+	"min": internal.AggregateFunctionDescriptor("min({0})", []internal.ExprType{
+		internal.ExprTypeIntIdentifier, internal.ExprTypeFloatIdentifier, internal.ExprTypeStringIdentifier, internal.ExprTypeTimestampIdentifier,
+	}),
+	"max": internal.AggregateFunctionDescriptor("max({0})", []internal.ExprType{
+		internal.ExprTypeIntIdentifier, internal.ExprTypeFloatIdentifier, internal.ExprTypeStringIdentifier, internal.ExprTypeTimestampIdentifier,
+	}),
+}
+
+// havingParserConfig overrides the builtins of Expr named like aggregates, e.g. count(array, predicate),
+// so that the aggregates are parsed as calls of functions
+var havingParserConfig = func() *conf.Config {
+	config := &conf.Config{Disabled: map[string]bool{}, Functions: conf.FunctionsTable{}}
+	for name := range aggregateFunctions {
+		config.Functions[name] = &builtin.Function{Name: name}
+	}
+	return config
+}()
+
+// parse parses the query, with aggregates overriding the builtins of Expr within HAVING conditions
+func (t *translator) parse(query string) (*parser.Tree, error) {
+	if t.having {
+		return parser.ParseWithConfig(query, havingParserConfig)
+	}
+	return parser.Parse(query)
+}
+
+func (t *translator) TranslateHaving(query string, groupBy string) (SQLHavingCondition, error) {
+	result, err := t.translateHaving(query, groupBy)
+	if err != nil {
+		return "", err
+	}
+	return SQLHavingCondition(t.inline(result)), nil
+}
+
+func (t *translator) TranslateHavingParams(query string, groupBy string) (SQLHavingCondition, []any, error) {
+	result, err := t.translateHaving(query, groupBy)
+	if err != nil {
+		return "", nil, err
+	}
+	return SQLHavingCondition(internal.BindArgs(result.Expr, t.dialect.placeholder)), result.Args, nil
+}
+
+func (t *translator) TranslateGroupBy(groupBy string) (SQLGroupBy, error) {
+	keys, err := t.translateGroupBy(groupBy)
+	if err != nil {
+		return "", err
+	}
+	var result internal.TranslationResult
+	items := make([]string, 0, len(keys))
+	for _, key := range keys {
+		items = append(items, key.Expr)
+		result.Args = append(result.Args, key.Args...)
+	}
+	result.Expr = strings.Join(items, ", ")
+	return SQLGroupBy(t.inline(result)), nil
+}
+
+// translateHaving translates the query by a copy of the translator which calls aggregates and references
+// the grouping keys. Comparisons of JSON properties are kept, since conditions on whole JSON columns
+// cannot be evaluated for groups.
+func (t *translator) translateHaving(query string, groupBy string) (internal.TranslationResult, error) {
+	scoped := *t
+	scoped.having = true
+	scoped.jsonContainment = false
+	if strings.TrimSpace(groupBy) != "" {
+		keys, err := t.translateGroupBy(groupBy)
+		if err != nil {
+			return internal.TranslationResult{}, err
+		}
+		for _, key := range keys {
+			scoped.groupingKeys = append(scoped.groupingKeys, key.Expr)
+		}
+	}
+	return scoped.translateCondition(query)
+}
+
+func (t *translator) translateGroupBy(groupBy string) ([]internal.TranslationResult, error) {
+	var keys []internal.TranslationResult
+	for _, item := range splitList(groupBy) {
+		expr, offset, err := listExpr(groupBy, item, len(strings.TrimRight(item.text, " \t\r\n")), "missing grouping key")
+		if err != nil {
+			return nil, bindSource(err, groupBy)
+		}
+		key, err := t.translateKey(groupBy, offset, expr, "grouping by")
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// translateAggregate translates the aggregate of the argument over the rows of the group,
+// which cannot be nested within other aggregates or array predicates
+func (t *translator) translateAggregate(node ast.Node, name string, descriptor internal.FunctionDescriptor, argNodes []ast.Node) (internal.TranslationResult, error) {
+	if t.aggregated || t.depth > 0 {
+		return internal.TranslationResult{}, unsupportedOperation(node, fmt.Sprintf("%v nested within an aggregate or array predicate", node))
+	}
+	if name == "count" && len(argNodes) == 0 {
+		return internal.TranslationResult{Expr: "count(*)", Type: internal.ExprTypeIntIdentifier}, nil
+	}
+	scoped := *t
+	scoped.aggregated = true
+	return scoped.callFunction(node, descriptor, argNodes)
+}
+
+// grouped checks that the column or JSON property referenced outside of aggregates of a HAVING condition
+// is a grouping key, as other values differ between the rows of the group
+func (t *translator) grouped(node ast.Node, translated internal.TranslationResult) error {
+	if !t.having || t.aggregated || slices.Contains(t.groupingKeys, translated.Expr) {
+		return nil
+	}
+	return unsupportedOperation(node, fmt.Sprintf("%v is neither a grouping key nor aggregated", node))
+}
+
+// elementProperty reports whether the member is a property of the array element referenced as # within a predicate
+func elementProperty(node *ast.MemberNode) bool {
+	var root ast.Node = node
+	for member, ok := root.(*ast.MemberNode); ok; member, ok = root.(*ast.MemberNode) {
+		root = member.Node
+	}
+	_, ok := root.(*ast.PointerNode)
+	return ok
+}
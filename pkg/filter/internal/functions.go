@@ -0,0 +1,241 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type FunctionDescriptor struct {
+	ArgTypes     [][]ExprType // accepted types of each argument
+	FnTranslator func(args []TranslationResult) (TranslationResult, error)
+}
+
+var templateArg = regexp.MustCompile(`\{(\d+)}`)
+
+// TemplateFunctionDescriptor formats the SQL template with arguments referenced as {0}, {1}, ...
+// Bind arguments follow the order in which the arguments appear in the template.
+func TemplateFunctionDescriptor(template string, argTypes [][]ExprType, resultType ExprType) FunctionDescriptor {
+	return FunctionDescriptor{
+		ArgTypes: argTypes,
+		FnTranslator: func(args []TranslationResult) (TranslationResult, error) {
+			result := FormatTemplate(template, args...)
+			result.Type = resultType
+			return result, nil
+		},
+	}
+}
+
+// FormatTemplate formats the SQL template with arguments referenced as {0}, {1}, ..., keeping the bind arguments
+// in the order of their placeholders
+func FormatTemplate(template string, args ...TranslationResult) TranslationResult {
+	var result TranslationResult
+	var sb strings.Builder
+	last := 0
+	for _, match := range templateArg.FindAllStringSubmatchIndex(template, -1) {
+		index, _ := strconv.Atoi(template[match[2]:match[3]])
+		if index >= len(args) {
+			continue
+		}
+		sb.WriteString(EscapePlaceholders(template[last:match[0]]))
+		sb.WriteString(args[index].Expr)
+		result.Args = append(result.Args, args[index].Args...)
+		last = match[1]
+	}
+	sb.WriteString(EscapePlaceholders(template[last:]))
+	result.Expr = sb.String()
+	return result
+}
+
+// StringFunctionDescriptor transforms a string identifier, keeping the result usable with string operators
+func StringFunctionDescriptor(template string) FunctionDescriptor {
+	return TemplateFunctionDescriptor(template, [][]ExprType{{ExprTypeStringIdentifier}}, ExprTypeStringIdentifier)
+}
+
+// LengthFunctionDescriptor counts characters of a string
+func LengthFunctionDescriptor(template string) FunctionDescriptor {
+	return TemplateFunctionDescriptor(template, [][]ExprType{{ExprTypeStringIdentifier, ExprTypeString}}, ExprTypeIntIdentifier)
+}
+
+// ArrayLengthFunctionDescriptor counts elements of an array identifier
+func ArrayLengthFunctionDescriptor(template string) FunctionDescriptor {
+	argTypes := make([]ExprType, 0, len(ScalarIdentifierTypes))
+	for _, elemType := range ScalarIdentifierTypes {
+		argTypes = append(argTypes, ArrayOf(elemType))
+	}
+	return TemplateFunctionDescriptor(template, [][]ExprType{argTypes}, ExprTypeIntIdentifier)
+}
+
+// JSONArrayLengthFunctionDescriptor counts elements of a JSON array
+func JSONArrayLengthFunctionDescriptor(template string) FunctionDescriptor {
+	argTypes := []ExprType{JSONArrayOf(ExprTypeJSONIdentifier)}
+	for _, elemType := range ScalarIdentifierTypes {
+		argTypes = append(argTypes, JSONArrayOf(elemType))
+	}
+	return TemplateFunctionDescriptor(template, [][]ExprType{argTypes}, ExprTypeIntIdentifier)
+}
+
+// CombinedFunctionDescriptor translates the call with the first of the descriptors accepting the argument types,
+// e.g. to overload the function for arguments of other types. All descriptors need the same number of arguments.
+func CombinedFunctionDescriptor(descriptors ...FunctionDescriptor) FunctionDescriptor {
+	argTypes := make([][]ExprType, len(descriptors[0].ArgTypes))
+	for _, descriptor := range descriptors {
+		for i, types := range descriptor.ArgTypes {
+			argTypes[i] = append(argTypes[i], types...)
+		}
+	}
+	return FunctionDescriptor{
+		ArgTypes: argTypes,
+		FnTranslator: func(args []TranslationResult) (TranslationResult, error) {
+			types := make([]ExprType, 0, len(args))
+			for _, arg := range args {
+				types = append(types, arg.Type)
+			}
+			for _, descriptor := range descriptors {
+				if descriptor.AcceptsArgs(types) {
+					return descriptor.FnTranslator(args)
+				}
+			}
+			return descriptors[0].FnTranslator(args)
+		},
+	}
+}
+
+// NumericFunctionDescriptor transforms a number, resulting in a number of the same kind
+func NumericFunctionDescriptor(template string) FunctionDescriptor {
+	return FunctionDescriptor{
+		ArgTypes: [][]ExprType{{ExprTypeIntIdentifier, ExprTypeInt, ExprTypeFloatIdentifier, ExprTypeFloat}},
+		FnTranslator: func(args []TranslationResult) (TranslationResult, error) {
+			result := FormatTemplate(template, args...)
+			result.Type = ExprTypeFloatIdentifier
+			if args[0].Type == ExprTypeIntIdentifier || args[0].Type == ExprTypeInt {
+				result.Type = ExprTypeIntIdentifier
+			}
+			return result, nil
+		},
+	}
+}
+
+// IntegerNumericFunctionDescriptor is a NumericFunctionDescriptor for dialects whose function results in a float
+// for an integer, converting the result of an integer back to an integer with the cast template, e.g. "cast({0} as bigint)"
+func IntegerNumericFunctionDescriptor(template, castTemplate string) FunctionDescriptor {
+	descriptor := NumericFunctionDescriptor(template)
+	fnTranslator := descriptor.FnTranslator
+	descriptor.FnTranslator = func(args []TranslationResult) (TranslationResult, error) {
+		result, err := fnTranslator(args)
+		if err != nil || result.Type != ExprTypeIntIdentifier {
+			return result, err
+		}
+		cast := FormatTemplate(castTemplate, result)
+		cast.Type = result.Type
+		return cast, nil
+	}
+	return descriptor
+}
+
+// AggregateFunctionDescriptor aggregates values of the rows of a group, resulting in a value of the same type, like max
+func AggregateFunctionDescriptor(template string, argTypes []ExprType) FunctionDescriptor {
+	return FunctionDescriptor{
+		ArgTypes: [][]ExprType{argTypes},
+		FnTranslator: func(args []TranslationResult) (TranslationResult, error) {
+			result := FormatTemplate(template, args...)
+			result.Type = args[0].Type
+			result.CaseInsensitive = args[0].CaseInsensitive
+			return result, nil
+		},
+	}
+}
+
+// DatePartFunctionDescriptor extracts an integer part of a timestamp, like the year or the hour
+func DatePartFunctionDescriptor(template string) FunctionDescriptor {
+	return TemplateFunctionDescriptor(template, [][]ExprType{{ExprTypeTimestampIdentifier, ExprTypeTimestamp}}, ExprTypeIntIdentifier)
+}
+
+// DateFunctionDescriptor parses a string literal to a timestamp, or converts a string identifier to a timestamp
+// with the cast template. Timestamps are truncated to the start of their day, with the trunc template unless literal.
+func DateFunctionDescriptor(castTemplate, truncTemplate string) FunctionDescriptor {
+	return FunctionDescriptor{
+		ArgTypes: [][]ExprType{{ExprTypeString, ExprTypeStringIdentifier, ExprTypeTimestamp, ExprTypeTimestampIdentifier}},
+		FnTranslator: func(args []TranslationResult) (TranslationResult, error) {
+			if IsTimestamp(args[0].Type) && IsLiteral(args[0]) {
+				return Literal(TruncateDay(args[0].Args[0].(time.Time)), ExprTypeTimestamp), nil
+			}
+			if !IsLiteral(args[0]) {
+				template := castTemplate
+				if IsTimestamp(args[0].Type) {
+					template = truncTemplate
+				}
+				result := FormatTemplate(template, args...)
+				result.Type = ExprTypeTimestampIdentifier
+				return result, nil
+			}
+			t, err := ParseDate(args[0].Args[0].(string))
+			if err != nil {
+				return TranslationResult{}, err
+			}
+			return Literal(t.UTC(), ExprTypeTimestamp), nil
+		},
+	}
+}
+
+// TruncateDay truncates the timestamp to the start of its day
+func TruncateDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// dateLayouts are the layouts accepted by the date builtin of Expr
+var dateLayouts = []string{
+	"2006-01-02",
+	"15:04:05",
+	"2006-01-02 15:04:05",
+	time.RFC3339Nano,
+	time.RFC822,
+	time.RFC850,
+	time.RFC1123,
+}
+
+// ParseDate parses the date in any of the layouts supported by the date builtin of Expr
+func ParseDate(date string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, date); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %v", date)
+}
+
+// DurationFunctionDescriptor parses a string literal in Go duration format to a duration,
+// formatted by the dialect
+func DurationFunctionDescriptor(format func(d time.Duration) TranslationResult) FunctionDescriptor {
+	return FunctionDescriptor{
+		ArgTypes: [][]ExprType{{ExprTypeString}},
+		FnTranslator: func(args []TranslationResult) (TranslationResult, error) {
+			if !IsLiteral(args[0]) {
+				return TranslationResult{}, fmt.Errorf("duration needs a string literal")
+			}
+			d, err := time.ParseDuration(args[0].Args[0].(string))
+			if err != nil {
+				return TranslationResult{}, err
+			}
+			result := format(d)
+			result.Type = ExprTypeDuration
+			return result, nil
+		},
+	}
+}
+
+// AcceptsArgs reports whether the argument types match the descriptor
+func (d FunctionDescriptor) AcceptsArgs(argTypes []ExprType) bool {
+	if len(argTypes) != len(d.ArgTypes) {
+		return false
+	}
+	for i, argType := range argTypes {
+		if !slices.Contains(d.ArgTypes[i], argType) {
+			return false
+		}
+	}
+	return true
+}
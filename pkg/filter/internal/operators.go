@@ -3,6 +3,8 @@ package internal
 import (
 	"fmt"
 	"slices"
+	"strconv"
+	"strings"
 )
 
 type BinaryOperatorTypeConstraint struct {
@@ -17,16 +19,18 @@ type BinaryOperatorDescriptor struct {
 
 func ComparisonOperatorDescriptor(op string) BinaryOperatorDescriptor {
 	return BinaryOperatorDescriptor{
-		TypeConstraints: []BinaryOperatorTypeConstraint{
-			{Left: ExprTypeIntIdentifier, Right: ExprTypeInt},
-			{Left: ExprTypeFloatIdentifier, Right: ExprTypeFloat},
-			{Left: ExprTypeStringIdentifier, Right: ExprTypeString},
-			{Left: ExprTypeTimestampIdentifier, Right: ExprTypeTimestamp},
-		},
+		TypeConstraints: comparisonTypeConstraints(
+			[2]ExprType{ExprTypeIntIdentifier, ExprTypeInt},
+			[2]ExprType{ExprTypeFloatIdentifier, ExprTypeFloat},
+			[2]ExprType{ExprTypeStringIdentifier, ExprTypeString},
+			[2]ExprType{ExprTypeTimestampIdentifier, ExprTypeTimestamp},
+			[2]ExprType{ExprTypeDuration},
+		),
 		OpTranslator: func(left, right TranslationResult) TranslationResult {
 			return TranslationResult{
 				Expr: fmt.Sprintf("%v %v %v", left.Expr, op, right.Expr),
 				Type: ExprTypeBool,
+				Args: slices.Concat(left.Args, right.Args),
 			}
 		},
 	}
@@ -35,32 +39,150 @@ func ComparisonOperatorDescriptor(op string) BinaryOperatorDescriptor {
 // NillableComparisonOperatorDescriptor is a special ComparisonOperatorDescriptor which handles NULL comparison
 func NillableComparisonOperatorDescriptor(op, nilOp string) BinaryOperatorDescriptor {
 	return BinaryOperatorDescriptor{
-		TypeConstraints: []BinaryOperatorTypeConstraint{
+		TypeConstraints: append([]BinaryOperatorTypeConstraint{
 			{Left: ExprTypeIntIdentifier, Right: ExprTypeNil},
 			{Left: ExprTypeFloatIdentifier, Right: ExprTypeNil},
 			{Left: ExprTypeBoolIdentifier, Right: ExprTypeNil},
 			{Left: ExprTypeStringIdentifier, Right: ExprTypeNil},
 			{Left: ExprTypeTimestampIdentifier, Right: ExprTypeNil},
-
-			{Left: ExprTypeIntIdentifier, Right: ExprTypeInt},
-			{Left: ExprTypeFloatIdentifier, Right: ExprTypeFloat},
-			{Left: ExprTypeBoolIdentifier, Right: ExprTypeBool},
-			{Left: ExprTypeStringIdentifier, Right: ExprTypeString},
-			{Left: ExprTypeTimestampIdentifier, Right: ExprTypeTimestamp},
-		},
+			{Left: ExprTypeNil, Right: ExprTypeIntIdentifier},
+			{Left: ExprTypeNil, Right: ExprTypeFloatIdentifier},
+			{Left: ExprTypeNil, Right: ExprTypeBoolIdentifier},
+			{Left: ExprTypeNil, Right: ExprTypeStringIdentifier},
+			{Left: ExprTypeNil, Right: ExprTypeTimestampIdentifier},
+		}, comparisonTypeConstraints(
+			[2]ExprType{ExprTypeIntIdentifier, ExprTypeInt},
+			[2]ExprType{ExprTypeFloatIdentifier, ExprTypeFloat},
+			[2]ExprType{ExprTypeBoolIdentifier, ExprTypeBool},
+			[2]ExprType{ExprTypeStringIdentifier, ExprTypeString},
+			[2]ExprType{ExprTypeTimestampIdentifier, ExprTypeTimestamp},
+			[2]ExprType{ExprTypeDuration},
+		)...),
 		OpTranslator: func(left, right TranslationResult) TranslationResult {
 			var actualOp = op
+			if left.Type == ExprTypeNil { // NULL IS x is not valid SQL
+				left, right = right, left
+			}
 			if right.Type == ExprTypeNil {
 				actualOp = nilOp
 			}
 			return TranslationResult{
 				Expr: fmt.Sprintf("%v %v %v", left.Expr, actualOp, right.Expr),
 				Type: ExprTypeBool,
+				Args: slices.Concat(left.Args, right.Args),
+			}
+		},
+	}
+}
+
+// comparisonTypeConstraints allows comparison of any combination of an identifier and a literal
+// (or computed expression) of the same type, given as {identifier type, literal type} pairs, and of mixed numeric types.
+// Types without identifiers, like durations, are given as {literal type}.
+func comparisonTypeConstraints(types ...[2]ExprType) []BinaryOperatorTypeConstraint {
+	var typeConstraints []BinaryOperatorTypeConstraint
+	for _, t := range types {
+		pair := t[:]
+		if t[1] == "" {
+			pair = t[:1]
+		}
+		for _, left := range pair {
+			for _, right := range pair {
+				typeConstraints = append(typeConstraints, BinaryOperatorTypeConstraint{Left: left, Right: right})
+			}
+		}
+	}
+	return append(typeConstraints, mixedNumericTypeConstraints...)
+}
+
+// mixedNumericTypeConstraints allow comparison of int and float expressions, whether identifiers, literals
+// or computed expressions, e.g. price >= minPrice or CASE WHEN ... THEN floatField ELSE 0 END < 100
+var mixedNumericTypeConstraints = []BinaryOperatorTypeConstraint{
+	{Left: ExprTypeIntIdentifier, Right: ExprTypeFloatIdentifier},
+	{Left: ExprTypeFloatIdentifier, Right: ExprTypeIntIdentifier},
+	{Left: ExprTypeIntIdentifier, Right: ExprTypeFloat},
+	{Left: ExprTypeFloatIdentifier, Right: ExprTypeInt},
+	{Left: ExprTypeInt, Right: ExprTypeFloatIdentifier},
+	{Left: ExprTypeFloat, Right: ExprTypeIntIdentifier},
+	{Left: ExprTypeInt, Right: ExprTypeFloat},
+	{Left: ExprTypeFloat, Right: ExprTypeInt},
+}
+
+// MembershipOperatorDescriptor checks presence of a value in an array literal, where empty arrays
+// result in the emptyResult constant as SQL does not allow empty value lists. Like comparisons,
+// int and float values can be members of arrays of the other numeric type.
+func MembershipOperatorDescriptor(op string, emptyResult bool) BinaryOperatorDescriptor {
+	typeConstraints := []BinaryOperatorTypeConstraint{
+		{Left: ExprTypeIntIdentifier, Right: ArrayOf(ExprTypeFloat)},
+		{Left: ExprTypeFloatIdentifier, Right: ArrayOf(ExprTypeInt)},
+	}
+	for identifierType, literalType := range map[ExprType]ExprType{
+		ExprTypeIntIdentifier:       ExprTypeInt,
+		ExprTypeFloatIdentifier:     ExprTypeFloat,
+		ExprTypeBoolIdentifier:      ExprTypeBool,
+		ExprTypeStringIdentifier:    ExprTypeString,
+		ExprTypeTimestampIdentifier: ExprTypeTimestamp,
+	} {
+		typeConstraints = append(typeConstraints,
+			BinaryOperatorTypeConstraint{Left: identifierType, Right: ArrayOf(literalType)},
+			BinaryOperatorTypeConstraint{Left: identifierType, Right: ArrayOf(ExprTypeNil)},
+		)
+	}
+	return BinaryOperatorDescriptor{
+		TypeConstraints: typeConstraints,
+		OpTranslator: func(left, right TranslationResult) TranslationResult {
+			if right.Type == ArrayOf(ExprTypeNil) {
+				return TranslationResult{Expr: strings.ToUpper(strconv.FormatBool(emptyResult)), Type: ExprTypeBool}
+			}
+			return TranslationResult{
+				Expr: fmt.Sprintf("%v %v %v", left.Expr, op, right.Expr),
+				Type: ExprTypeBool,
+				Args: slices.Concat(left.Args, right.Args),
 			}
 		},
 	}
 }
 
+// JSONArrayMembershipOperatorDescriptor checks presence of a number or string value in a JSON array with the template,
+// referencing the value as {0} and the array as {1}. Timestamps and booleans are left out, as JSON holds them
+// as strings and true/false, which do not compare equal to SQL values in every database.
+func JSONArrayMembershipOperatorDescriptor(template string) BinaryOperatorDescriptor {
+	var typeConstraints []BinaryOperatorTypeConstraint
+	for _, identifierType := range []ExprType{ExprTypeIntIdentifier, ExprTypeFloatIdentifier, ExprTypeStringIdentifier} {
+		typeConstraints = append(typeConstraints,
+			BinaryOperatorTypeConstraint{Left: identifierType, Right: JSONArrayOf(identifierType)},
+			BinaryOperatorTypeConstraint{Left: literalTypes[identifierType], Right: JSONArrayOf(identifierType)},
+		)
+	}
+	return BinaryOperatorDescriptor{
+		TypeConstraints: typeConstraints,
+		OpTranslator: func(left, right TranslationResult) TranslationResult {
+			result := FormatTemplate(template, left, right)
+			result.Type = ExprTypeBool
+			return result
+		},
+	}
+}
+
+// ArrayMembershipOperatorDescriptor checks presence of a value in an array identifier with the template,
+// referencing the value as {0} and the array as {1}
+func ArrayMembershipOperatorDescriptor(template string) BinaryOperatorDescriptor {
+	var typeConstraints []BinaryOperatorTypeConstraint
+	for _, identifierType := range ScalarIdentifierTypes {
+		typeConstraints = append(typeConstraints,
+			BinaryOperatorTypeConstraint{Left: identifierType, Right: ArrayOf(identifierType)},
+			BinaryOperatorTypeConstraint{Left: literalTypes[identifierType], Right: ArrayOf(identifierType)},
+		)
+	}
+	return BinaryOperatorDescriptor{
+		TypeConstraints: typeConstraints,
+		OpTranslator: func(left, right TranslationResult) TranslationResult {
+			result := FormatTemplate(template, left, right)
+			result.Type = ExprTypeBool
+			return result
+		},
+	}
+}
+
 func BooleanOperatorDescriptor(op string) BinaryOperatorDescriptor {
 	return BinaryOperatorDescriptor{
 		TypeConstraints: []BinaryOperatorTypeConstraint{
@@ -73,12 +195,26 @@ func BooleanOperatorDescriptor(op string) BinaryOperatorDescriptor {
 			return TranslationResult{
 				Expr: fmt.Sprintf("%v %v %v", left.Expr, op, right.Expr),
 				Type: ExprTypeBool,
+				Args: slices.Concat(left.Args, right.Args),
 			}
 		},
 	}
 }
 
 func NumericOperatorDescriptor(op string) BinaryOperatorDescriptor {
+	return numericOperatorDescriptor(func(left, right TranslationResult) string {
+		return fmt.Sprintf("%v %v %v", left.Expr, op, right.Expr)
+	})
+}
+
+// NumericFunctionOperatorDescriptor is a NumericOperatorDescriptor for dialects which implement the operator as a function
+func NumericFunctionOperatorDescriptor(fn string) BinaryOperatorDescriptor {
+	return numericOperatorDescriptor(func(left, right TranslationResult) string {
+		return fmt.Sprintf("%v(%v, %v)", fn, left.Expr, right.Expr)
+	})
+}
+
+func numericOperatorDescriptor(format func(left, right TranslationResult) string) BinaryOperatorDescriptor {
 	intExprs := []ExprType{ExprTypeIntIdentifier, ExprTypeInt}
 	return BinaryOperatorDescriptor{
 		TypeConstraints: []BinaryOperatorTypeConstraint{
@@ -103,27 +239,151 @@ func NumericOperatorDescriptor(op string) BinaryOperatorDescriptor {
 				resultType = ExprTypeInt
 			}
 			return TranslationResult{
-				Expr: fmt.Sprintf("%v %v %v", left.Expr, op, right.Expr),
+				Expr: format(left, right),
 				Type: resultType,
+				Args: slices.Concat(left.Args, right.Args),
+			}
+		},
+	}
+}
+
+// TemporalOperatorDescriptor translates addition ("+") or subtraction ("-") of timestamps and durations, where
+// timestamp ± duration results in a timestamp, while timestamp - timestamp and duration ± duration result in a duration.
+// Since dialects represent durations differently, the format translates the operands, e.g. with FormatTemplate.
+func TemporalOperatorDescriptor(op string, format func(left, right TranslationResult) TranslationResult) BinaryOperatorDescriptor {
+	typeConstraints := []BinaryOperatorTypeConstraint{
+		{Left: ExprTypeTimestampIdentifier, Right: ExprTypeDuration},
+		{Left: ExprTypeTimestamp, Right: ExprTypeDuration},
+		{Left: ExprTypeDuration, Right: ExprTypeDuration},
+	}
+	if op == "+" {
+		typeConstraints = append(typeConstraints,
+			BinaryOperatorTypeConstraint{Left: ExprTypeDuration, Right: ExprTypeTimestampIdentifier},
+			BinaryOperatorTypeConstraint{Left: ExprTypeDuration, Right: ExprTypeTimestamp},
+		)
+	} else {
+		typeConstraints = append(typeConstraints,
+			BinaryOperatorTypeConstraint{Left: ExprTypeTimestampIdentifier, Right: ExprTypeTimestampIdentifier},
+			BinaryOperatorTypeConstraint{Left: ExprTypeTimestampIdentifier, Right: ExprTypeTimestamp},
+			BinaryOperatorTypeConstraint{Left: ExprTypeTimestamp, Right: ExprTypeTimestampIdentifier},
+			BinaryOperatorTypeConstraint{Left: ExprTypeTimestamp, Right: ExprTypeTimestamp},
+		)
+	}
+	return BinaryOperatorDescriptor{
+		TypeConstraints: typeConstraints,
+		OpTranslator: func(left, right TranslationResult) TranslationResult {
+			result := format(left, right)
+			result.Type = ExprTypeDuration
+			if IsTimestamp(left.Type) != IsTimestamp(right.Type) {
+				result.Type = ExprTypeTimestamp
 			}
+			return result
 		},
 	}
 }
 
+// IsTimestamp reports whether the type is a timestamp identifier, literal or computed expression
+func IsTimestamp(exprType ExprType) bool {
+	return exprType == ExprTypeTimestampIdentifier || exprType == ExprTypeTimestamp
+}
+
+// CombinedOperatorDescriptor translates the operation with the first of the descriptors accepting the operand types,
+// e.g. to extend the numeric operator with the arithmetic of other types
+func CombinedOperatorDescriptor(descriptors ...BinaryOperatorDescriptor) BinaryOperatorDescriptor {
+	var typeConstraints []BinaryOperatorTypeConstraint
+	for _, descriptor := range descriptors {
+		typeConstraints = append(typeConstraints, descriptor.TypeConstraints...)
+	}
+	return BinaryOperatorDescriptor{
+		TypeConstraints: typeConstraints,
+		OpTranslator: func(left, right TranslationResult) TranslationResult {
+			constraint := BinaryOperatorTypeConstraint{Left: left.Type, Right: right.Type}
+			for _, descriptor := range descriptors {
+				if slices.Contains(descriptor.TypeConstraints, constraint) {
+					return descriptor.OpTranslator(left, right)
+				}
+			}
+			return descriptors[0].OpTranslator(left, right)
+		},
+	}
+}
+
+// StringLikeOperatorDescriptor matches the value literally by escaping LIKE wildcards with a backslash,
+// which is the default LIKE escape character in PostgreSQL and MySQL
 func StringLikeOperatorDescriptor(prefix, suffix string) BinaryOperatorDescriptor {
+	return LikeOperatorDescriptor("%v like %v", prefix, suffix)
+}
+
+// LikeOperatorDescriptor is a StringLikeOperatorDescriptor with a custom format of the identifier and the pattern,
+// e.g. "%v ilike %v"
+func LikeOperatorDescriptor(format, prefix, suffix string) BinaryOperatorDescriptor {
+	return BinaryOperatorDescriptor{
+		TypeConstraints: []BinaryOperatorTypeConstraint{
+			{Left: ExprTypeStringIdentifier, Right: ExprTypeString},
+		},
+		OpTranslator: func(left, right TranslationResult) TranslationResult {
+			return TranslationResult{
+				Expr: fmt.Sprintf(format, left.Expr, Placeholder),
+				Type: ExprTypeBool,
+				Args: append(slices.Clone(left.Args), prefix+likeEscaper.Replace(right.Args[0].(string))+suffix),
+			}
+		},
+	}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// GlobOperatorDescriptor is a StringLikeOperatorDescriptor for dialects which match patterns case-sensitively with GLOB
+func GlobOperatorDescriptor(prefix, suffix string) BinaryOperatorDescriptor {
+	escaper := strings.NewReplacer("*", "[*]", "?", "[?]", "[", "[[]")
 	return BinaryOperatorDescriptor{
 		TypeConstraints: []BinaryOperatorTypeConstraint{
 			{Left: ExprTypeStringIdentifier, Right: ExprTypeString},
 		},
 		OpTranslator: func(left, right TranslationResult) TranslationResult {
 			return TranslationResult{
-				Expr: fmt.Sprintf("%v like '%v%v%v'", left.Expr, prefix, right.Expr[1:len(right.Expr)-1], suffix),
+				Expr: fmt.Sprintf("%v GLOB %v", left.Expr, Placeholder),
 				Type: ExprTypeBool,
+				Args: append(slices.Clone(left.Args), prefix+escaper.Replace(right.Args[0].(string))+suffix),
 			}
 		},
 	}
 }
 
+func RegexOperatorDescriptor(op string) BinaryOperatorDescriptor {
+	return BinaryOperatorDescriptor{
+		TypeConstraints: []BinaryOperatorTypeConstraint{
+			{Left: ExprTypeStringIdentifier, Right: ExprTypeString},
+		},
+		OpTranslator: func(left, right TranslationResult) TranslationResult {
+			return TranslationResult{
+				Expr: fmt.Sprintf("%v %v %v", left.Expr, op, right.Expr),
+				Type: ExprTypeBool,
+				Args: slices.Concat(left.Args, right.Args),
+			}
+		},
+	}
+}
+
+// CaseInsensitiveOperatorDescriptor applies the descriptor to lower case string operands, unless compared to NULL
+func CaseInsensitiveOperatorDescriptor(descriptor BinaryOperatorDescriptor) BinaryOperatorDescriptor {
+	opTranslator := descriptor.OpTranslator
+	descriptor.OpTranslator = func(left, right TranslationResult) TranslationResult {
+		if left.Type == ExprTypeNil || right.Type == ExprTypeNil {
+			return opTranslator(left, right)
+		}
+		return opTranslator(lower(left), lower(right))
+	}
+	return descriptor
+}
+
+func lower(result TranslationResult) TranslationResult {
+	if result.Type == ExprTypeString || result.Type == ExprTypeStringIdentifier {
+		result.Expr = fmt.Sprintf("lower(%v)", result.Expr)
+	}
+	return result
+}
+
 type UnaryOperatorDescriptor struct {
 	TypeConstraints []ExprType
 	OpTranslator    func(nested TranslationResult) TranslationResult
@@ -136,6 +396,7 @@ func UnaryBooleanOperatorDescriptor(op string) UnaryOperatorDescriptor {
 			return TranslationResult{
 				Expr: fmt.Sprintf("%v %v", op, expr.Expr),
 				Type: ExprTypeBool,
+				Args: expr.Args,
 			}
 		},
 	}
@@ -145,10 +406,25 @@ func UnaryNumericOperatorDescriptor(op string) UnaryOperatorDescriptor {
 	return UnaryOperatorDescriptor{
 		TypeConstraints: []ExprType{ExprTypeInt, ExprTypeFloat},
 		OpTranslator: func(expr TranslationResult) TranslationResult {
+			if op == "-" && IsLiteral(expr) { // negative literals are bound as negative values
+				return Literal(negate(expr.Args[0]), expr.Type)
+			}
 			return TranslationResult{
 				Expr: fmt.Sprintf("%v%v", op, expr.Expr),
 				Type: expr.Type,
+				Args: expr.Args,
 			}
 		},
 	}
 }
+
+func negate(value any) any {
+	switch v := value.(type) {
+	case int:
+		return -v
+	case float64:
+		return -v
+	default:
+		return value
+	}
+}
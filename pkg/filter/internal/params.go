@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"strings"
+)
+
+// Placeholder marks the position of a bind argument within TranslationResult.Expr.
+// Literal question marks in generated SQL have to be escaped as "??".
+const Placeholder = "?"
+
+func EscapePlaceholders(s string) string {
+	return strings.ReplaceAll(s, Placeholder, Placeholder+Placeholder)
+}
+
+// Literal creates a TranslationResult of a single bind argument
+func Literal(value any, exprType ExprType) TranslationResult {
+	return TranslationResult{Expr: Placeholder, Type: exprType, Args: []any{value}}
+}
+
+// IsLiteral reports whether the result consists only of a single bind argument
+func IsLiteral(result TranslationResult) bool {
+	return result.Expr == Placeholder && len(result.Args) == 1
+}
+
+// BindArgs replaces each placeholder in expr with the output of bind for the respective argument index
+// and unescapes literal question marks.
+func BindArgs(expr string, bind func(index int) string) string {
+	var sb strings.Builder
+	index := 0
+	for i := 0; i < len(expr); i++ {
+		if expr[i] != Placeholder[0] {
+			sb.WriteByte(expr[i])
+			continue
+		}
+		if i+1 < len(expr) && expr[i+1] == Placeholder[0] {
+			sb.WriteByte(expr[i])
+			i++
+			continue
+		}
+		sb.WriteString(bind(index))
+		index++
+	}
+	return sb.String()
+}
@@ -1,5 +1,10 @@
 package internal
 
+import (
+	"slices"
+	"strings"
+)
+
 type ExprType string
 
 const (
@@ -9,6 +14,7 @@ const (
 	ExprTypeBool      ExprType = "expr_bool"
 	ExprTypeString    ExprType = "expr_string"
 	ExprTypeTimestamp ExprType = "expr_timestamp"
+	ExprTypeDuration  ExprType = "expr_duration"
 
 	ExprTypeIntIdentifier       ExprType = "int"
 	ExprTypeFloatIdentifier     ExprType = "float"
@@ -16,9 +22,137 @@ const (
 	ExprTypeStringIdentifier    ExprType = "string"
 	ExprTypeTimestampIdentifier ExprType = "timestamp"
 	ExprTypeJSONIdentifier      ExprType = "json"
+
+	ExprTypeJSONDynamic ExprType = "json_dynamic" // JSON value of a type not known until inferred or cast
+	ExprTypeRelation    ExprType = "relation"     // row of a foreign table, where rows of to-many relations are arrays
 )
 
+// ArrayOf returns the type of arrays with elements of the given type
+func ArrayOf(elemType ExprType) ExprType {
+	return elemType + "[]"
+}
+
+// ScalarIdentifierTypes are the identifier types which can be elements of array identifiers
+var ScalarIdentifierTypes = []ExprType{
+	ExprTypeIntIdentifier,
+	ExprTypeFloatIdentifier,
+	ExprTypeBoolIdentifier,
+	ExprTypeStringIdentifier,
+	ExprTypeTimestampIdentifier,
+}
+
+// ElemType returns the element type of array identifier types, e.g. int for int[]
+func ElemType(arrayType ExprType) (ExprType, bool) {
+	elemType, ok := strings.CutSuffix(string(arrayType), "[]")
+	if !ok || !slices.Contains(ScalarIdentifierTypes, ExprType(elemType)) {
+		return "", false
+	}
+	return ExprType(elemType), true
+}
+
+// JSONArrayOf returns the type of JSON arrays with elements of the given type, where arrays of objects or arrays are json[]
+func JSONArrayOf(elemType ExprType) ExprType {
+	if !slices.Contains(ScalarIdentifierTypes, elemType) {
+		return ArrayOf(ExprTypeJSONIdentifier)
+	}
+	return "json_" + ArrayOf(elemType)
+}
+
+// JSONElemType returns the element type of JSON array types, e.g. int for json_int[] and json for json[]
+func JSONElemType(arrayType ExprType) (ExprType, bool) {
+	elemType, ok := strings.CutSuffix(strings.TrimPrefix(string(arrayType), "json_"), "[]")
+	if !ok || JSONArrayOf(ExprType(elemType)) != arrayType {
+		return "", false
+	}
+	return ExprType(elemType), true
+}
+
+// IsJSON reports whether expressions of the type are JSON values, i.e. JSON objects or arrays
+func IsJSON(t ExprType) bool {
+	_, isArray := JSONElemType(t)
+	return t == ExprTypeJSONIdentifier || t == ExprTypeJSONDynamic || isArray
+}
+
+// InferredType returns the scalar identifier type which dynamic JSON values are cast to when they are operands
+// of an operator along with an operand of the given type, e.g. string for comparison with a string literal or
+// membership in an array of strings. Dynamic values compared with nil are strings, as any JSON value can be text,
+// and those compared with numbers are floats, as JSON does not tell integers from other numbers, like 3.5.
+func InferredType(operandType ExprType) (ExprType, bool) {
+	if elemType, ok := strings.CutSuffix(string(operandType), "[]"); ok {
+		operandType = ExprType(strings.TrimPrefix(elemType, "json_"))
+	}
+	if operandType == ExprTypeNil {
+		return ExprTypeStringIdentifier, true
+	}
+	if identifierType, ok := identifierTypes[operandType]; ok {
+		operandType = identifierType
+	}
+	if operandType == ExprTypeIntIdentifier {
+		return ExprTypeFloatIdentifier, true
+	}
+	return operandType, slices.Contains(ScalarIdentifierTypes, operandType)
+}
+
 type TranslationResult struct {
 	Expr string
 	Type ExprType
+	Args []any // bind arguments in order of their placeholders in Expr
+
+	CaseInsensitive bool // string operators ignore case of the expression
+	Precedence      int  // precedence of the outermost operator of Expr, which is not parenthesized, 0 for atomic expressions
+}
+
+var literalTypes = map[ExprType]ExprType{
+	ExprTypeIntIdentifier:       ExprTypeInt,
+	ExprTypeFloatIdentifier:     ExprTypeFloat,
+	ExprTypeBoolIdentifier:      ExprTypeBool,
+	ExprTypeStringIdentifier:    ExprTypeString,
+	ExprTypeTimestampIdentifier: ExprTypeTimestamp,
+}
+
+var identifierTypes = map[ExprType]ExprType{
+	ExprTypeInt:       ExprTypeIntIdentifier,
+	ExprTypeFloat:     ExprTypeFloatIdentifier,
+	ExprTypeBool:      ExprTypeBoolIdentifier,
+	ExprTypeString:    ExprTypeStringIdentifier,
+	ExprTypeTimestamp: ExprTypeTimestampIdentifier,
+}
+
+// UnifiedType returns the type of an expression resulting in either of the operands, like CASE or COALESCE.
+// It is an identifier type even for literal operands, as the result is computed by the database like an identifier,
+// and supports the operators of the identifier, but not those which need literals, like LIKE patterns.
+// Mixed int and float operands result in float, and nil operands take the type of the other one.
+func UnifiedType(left, right ExprType) (ExprType, bool) {
+	switch {
+	case left == ExprTypeNil && right == ExprTypeNil:
+		return ExprTypeNil, true
+	case left == ExprTypeNil:
+		left = right
+	case right == ExprTypeNil:
+		right = left
+	}
+	leftLiteral, ok := literalTypes[left]
+	if !ok {
+		leftLiteral = left
+	}
+	rightLiteral, ok := literalTypes[right]
+	if !ok {
+		rightLiteral = right
+	}
+	result := leftLiteral
+	if leftLiteral != rightLiteral {
+		numeric := []ExprType{ExprTypeInt, ExprTypeFloat}
+		if !slices.Contains(numeric, leftLiteral) || !slices.Contains(numeric, rightLiteral) {
+			return "", false
+		}
+		result = ExprTypeFloat
+	}
+	identifierType, ok := identifierTypes[result]
+	return identifierType, ok
+}
+
+// LiteralType returns the type of literals matching the identifier type
+func LiteralType(identifierType ExprType) (ExprType, bool) {
+	t, ok := literalTypes[identifierType]
+	return t, ok
 }
@@ -1,6 +1,12 @@
 package filter
 
-import "github.com/happening-oss/expr2sql/pkg/filter/internal"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/happening-oss/expr2sql/pkg/filter/internal"
+)
 
 type IdentifierType internal.ExprType
 
@@ -11,8 +17,15 @@ const (
 	IdentifierTypeString    = IdentifierType(internal.ExprTypeStringIdentifier)
 	IdentifierTypeTimestamp = IdentifierType(internal.ExprTypeTimestampIdentifier)
 	IdentifierTypeJSON      = IdentifierType(internal.ExprTypeJSONIdentifier)
+	IdentifierTypeRelation  = IdentifierType(internal.ExprTypeRelation)
 )
 
+// IdentifierTypeArray is the type of array columns with elements of the scalar type, e.g. text[] in PostgreSQL.
+// MySQL and SQLite store arrays as JSON arrays.
+func IdentifierTypeArray(elemType IdentifierType) IdentifierType {
+	return IdentifierType(internal.ArrayOf(internal.ExprType(elemType)))
+}
+
 type JSONElement interface {
 	IdentifierType() IdentifierType
 }
@@ -29,9 +42,182 @@ func (l JSONLeaf) IdentifierType() IdentifierType {
 	return IdentifierType(l)
 }
 
+// JSONTimestampFormat is the format in which a JSON timestamp property is stored
+type JSONTimestampFormat byte
+
+const (
+	JSONTimestampFormatRFC3339      JSONTimestampFormat = iota // string like "2024-09-17T08:00:00+03:00", as encoded by encoding/json
+	JSONTimestampFormatEpochSeconds                            // number of seconds since the unix epoch, possibly with a fraction
+	JSONTimestampFormatEpochMillis                             // number of milliseconds since the unix epoch
+)
+
+// JSONTimestamp describes a timestamp property stored in the format, e.g. JSONTimestamp(JSONTimestampFormatEpochMillis),
+// while JSONLeaf(IdentifierTypeTimestamp) is stored in RFC3339. Timestamps are converted to those of the database,
+// so that they are compared chronologically.
+func JSONTimestamp(format JSONTimestampFormat) JSONElement {
+	return jsonTimestamp{format: format}
+}
+
+type jsonTimestamp struct {
+	format JSONTimestampFormat
+}
+
+func (jsonTimestamp) IdentifierType() IdentifierType {
+	return IdentifierTypeTimestamp
+}
+
+// jsonTimestampFormat returns the format of the JSON timestamp, which is RFC3339 unless specified otherwise
+func jsonTimestampFormat(element JSONElement) JSONTimestampFormat {
+	if timestamp, ok := element.(jsonTimestamp); ok {
+		return timestamp.format
+	}
+	return JSONTimestampFormatRFC3339
+}
+
+// storedType returns the type of the JSON value in which timestamps of the format are stored
+func (f JSONTimestampFormat) storedType() internal.ExprType {
+	if f == JSONTimestampFormatRFC3339 {
+		return internal.ExprTypeStringIdentifier
+	}
+	return internal.ExprTypeFloatIdentifier
+}
+
+// JSONArray describes a JSON array with elements described by the element spec,
+// e.g. JSONArray(JSONTree{"sku": JSONLeaf(IdentifierTypeString)})
+func JSONArray(element JSONElement) JSONElement {
+	return jsonArray{element: element}
+}
+
+type jsonArray struct {
+	element JSONElement
+}
+
+func (a jsonArray) IdentifierType() IdentifierType {
+	return IdentifierType(internal.JSONArrayOf(internal.ExprType(a.element.IdentifierType())))
+}
+
+// JSONDynamic describes a JSON object with keys not known in advance, e.g. user-defined attributes, optionally
+// restricted to keys matching the pattern. Its properties are dynamic objects too, and their type is inferred from
+// the other operand of the operator, e.g. attrs.size > 3, or given by a cast function, e.g. int(attrs.size).
+func JSONDynamic(keys *regexp.Regexp) JSONElement {
+	return jsonDynamic{keys: keys}
+}
+
+type jsonDynamic struct {
+	keys *regexp.Regexp
+}
+
+func (jsonDynamic) IdentifierType() IdentifierType {
+	return IdentifierType(internal.ExprTypeJSONDynamic)
+}
+
+// Relation describes the rows of a foreign table related to the row, e.g. the customer of an order or its line items.
+// Properties of a single related row are selected by correlated subqueries, e.g. customer.country == "DE",
+// while many related rows are filtered by array predicates translated to EXISTS subqueries,
+// e.g. any(lineItems, .sku startsWith "X").
+type Relation struct {
+	Table       string       // foreign table, optionally qualified by the schema
+	Column      string       // column of the foreign table equal to the column of the relation identifier
+	Many        bool         // the row has many related rows instead of a single one
+	Identifiers []Identifier // columns of the foreign table, with unqualified names
+}
+
 type Identifier struct {
 	ExprName string
-	DBName   string
+	DBName   string // column name, defaults to ExprName, optionally qualified as table.column or schema.table.column
+	Table    string // table or its alias qualifying the column, e.g. in joins
 	Type     IdentifierType
-	JSONSpec JSONTree
+	JSONSpec JSONElement // JSONTree or JSONDynamic for JSON identifiers
+	Relation *Relation   // foreign table of relation identifiers, joined on their column, which needs to be qualified
+
+	CaseInsensitive bool // string operators ignore case of the identifier and its JSON string properties
+}
+
+// dbNamePart matches a name within the qualified column name, which is quoted by the dialect when needed
+var dbNamePart = regexp.MustCompile(`^[\p{L}_][\p{L}\p{N}_$]*$`)
+
+// qualifiedName returns the parts of the qualified column name, e.g. schema, table and column
+func (i Identifier) qualifiedName() []string {
+	name := i.ExprName
+	if i.DBName != "" {
+		name = i.DBName
+	}
+	parts := strings.Split(name, ".")
+	if i.Table != "" {
+		parts = append([]string{i.Table}, parts...)
+	}
+	return parts
+}
+
+// validate checks that the qualified column name consists of names only, rejecting SQL expressions,
+// and that relations are joined by columns which cannot be confused with those of the foreign table
+func (i Identifier) validate() error {
+	if err := i.validateName(); err != nil {
+		return err
+	}
+	if i.Type == IdentifierTypeRelation && len(i.qualifiedName()) < 2 {
+		return fmt.Errorf("%w %v: db name of relation needs to be qualified by the table", ErrInvalidIdentifier, i.ExprName)
+	}
+	return i.validateRelation(map[*Relation]bool{})
+}
+
+// validateRelation checks the foreign table of the relation along with its identifiers, which are qualified
+// by the alias of the table, skipping the relations which have already been visited
+func (i Identifier) validateRelation(visited map[*Relation]bool) error {
+	if i.Type != IdentifierTypeRelation {
+		return nil
+	}
+	if i.Relation == nil {
+		return fmt.Errorf("%w %v: relation without the foreign table", ErrInvalidIdentifier, i.ExprName)
+	}
+	if visited[i.Relation] {
+		return nil
+	}
+	visited[i.Relation] = true
+	if _, err := tableName(i.Relation.Table); err != nil {
+		return fmt.Errorf("%w %v: %w", ErrInvalidIdentifier, i.ExprName, err)
+	}
+	if !dbNamePart.MatchString(i.Relation.Column) {
+		return fmt.Errorf("%w %v: column %q of relation contains other than letters, digits, underscores and dollar signs", ErrInvalidIdentifier, i.ExprName, i.Relation.Column)
+	}
+	for _, identifier := range i.Relation.Identifiers {
+		if len(identifier.qualifiedName()) > 1 {
+			return fmt.Errorf("%w %v: db name of relation column %v needs to be unqualified", ErrInvalidIdentifier, i.ExprName, identifier.ExprName)
+		}
+		if err := identifier.validateName(); err != nil {
+			return err
+		}
+		if err := identifier.validateRelation(visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tableName returns the parts of the table name, optionally qualified by the schema
+func tableName(name string) ([]string, error) {
+	parts := strings.Split(name, ".")
+	if len(parts) > 2 {
+		return nil, fmt.Errorf("table name %q has more than schema and table", name)
+	}
+	for _, part := range parts {
+		if !dbNamePart.MatchString(part) {
+			return nil, fmt.Errorf("table name %q contains other than letters, digits, underscores and dollar signs", name)
+		}
+	}
+	return parts, nil
+}
+
+// validateName checks that the qualified column name consists of names only
+func (i Identifier) validateName() error {
+	parts := i.qualifiedName()
+	if len(parts) > 3 {
+		return fmt.Errorf("%w %v: db name %q has more than schema, table and column", ErrInvalidIdentifier, i.ExprName, strings.Join(parts, "."))
+	}
+	for _, part := range parts {
+		if !dbNamePart.MatchString(part) {
+			return fmt.Errorf("%w %v: db name %q contains other than letters, digits, underscores and dollar signs", ErrInvalidIdentifier, i.ExprName, strings.Join(parts, "."))
+		}
+	}
+	return nil
 }
@@ -0,0 +1,203 @@
+package filter
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/happening-oss/expr2sql/pkg/filter/internal"
+)
+
+var mysqlBinaryOperators = map[string]internal.BinaryOperatorDescriptor{
+	"in": internal.CombinedOperatorDescriptor(
+		internal.MembershipOperatorDescriptor("IN", false),
+		internal.ArrayMembershipOperatorDescriptor("{0} MEMBER OF({1})"),
+		internal.JSONArrayMembershipOperatorDescriptor("{0} MEMBER OF({1})"),
+	),
+	"not in": internal.CombinedOperatorDescriptor(
+		internal.MembershipOperatorDescriptor("NOT IN", true),
+		internal.ArrayMembershipOperatorDescriptor("NOT ({0} MEMBER OF({1}))"),
+		internal.JSONArrayMembershipOperatorDescriptor("NOT ({0} MEMBER OF({1}))"),
+	),
+
+	"+": internal.CombinedOperatorDescriptor(internal.NumericOperatorDescriptor("+"), internal.TemporalOperatorDescriptor("+", mysqlTemporal("+"))),
+	"-": internal.CombinedOperatorDescriptor(internal.NumericOperatorDescriptor("-"), internal.TemporalOperatorDescriptor("-", mysqlTemporal("-"))),
+
+	"**": internal.NumericFunctionOperatorDescriptor("POW"),
+	"^":  internal.NumericFunctionOperatorDescriptor("POW"),
+
+	"matches": internal.RegexOperatorDescriptor("REGEXP"),
+}
+
+// mysqlTemporal adds and subtracts timestamps and durations, which are numbers of microseconds
+func mysqlTemporal(op string) func(left, right internal.TranslationResult) internal.TranslationResult {
+	return func(left, right internal.TranslationResult) internal.TranslationResult {
+		switch {
+		case internal.IsTimestamp(left.Type) && internal.IsTimestamp(right.Type):
+			return internal.FormatTemplate("TIMESTAMPDIFF(MICROSECOND, {1}, {0})", left, right)
+		case internal.IsTimestamp(left.Type) && op == "-":
+			return internal.FormatTemplate("TIMESTAMPADD(MICROSECOND, -({1}), {0})", left, right)
+		case internal.IsTimestamp(left.Type):
+			return internal.FormatTemplate("TIMESTAMPADD(MICROSECOND, {1}, {0})", left, right)
+		case internal.IsTimestamp(right.Type):
+			return internal.FormatTemplate("TIMESTAMPADD(MICROSECOND, {0}, {1})", left, right)
+		default:
+			return internal.FormatTemplate("{0} "+op+" {1}", left, right)
+		}
+	}
+}
+
+var mysqlCaseInsensitiveBinaryOperators = map[string]internal.BinaryOperatorDescriptor{
+	"contains":   internal.LikeOperatorDescriptor("lower(%v) like lower(%v)", "%", "%"),
+	"startsWith": internal.LikeOperatorDescriptor("lower(%v) like lower(%v)", "", "%"),
+	"endsWith":   internal.LikeOperatorDescriptor("lower(%v) like lower(%v)", "%", ""),
+}
+
+// mysqlFunctions count characters instead of bytes, and keep timestamps in UTC with microsecond precision.
+// Durations are numbers of microseconds, since MySQL has no interval values.
+var mysqlFunctions = map[string]internal.FunctionDescriptor{
+	"len": internal.CombinedFunctionDescriptor(
+		internal.LengthFunctionDescriptor("CHAR_LENGTH({0})"),
+		internal.ArrayLengthFunctionDescriptor("JSON_LENGTH({0})"),
+		internal.JSONArrayLengthFunctionDescriptor("JSON_LENGTH({0})"),
+	),
+	"now":  internal.TemplateFunctionDescriptor("UTC_TIMESTAMP(6)", nil, internal.ExprTypeTimestampIdentifier),
+	"date": internal.DateFunctionDescriptor("CAST({0} AS DATETIME(6))", "CAST(DATE({0}) AS DATETIME(6))"),
+	"duration": internal.DurationFunctionDescriptor(func(d time.Duration) internal.TranslationResult {
+		return internal.TranslationResult{Expr: "?", Args: []any{int(d.Microseconds())}}
+	}),
+}
+
+var mysqlDateParts = map[string]internal.FunctionDescriptor{
+	"Year":    internal.DatePartFunctionDescriptor("YEAR({0})"),
+	"Month":   internal.DatePartFunctionDescriptor("MONTH({0})"),
+	"Day":     internal.DatePartFunctionDescriptor("DAY({0})"),
+	"Hour":    internal.DatePartFunctionDescriptor("HOUR({0})"),
+	"Minute":  internal.DatePartFunctionDescriptor("MINUTE({0})"),
+	"Second":  internal.DatePartFunctionDescriptor("SECOND({0})"),
+	"Weekday": internal.DatePartFunctionDescriptor("(DAYOFWEEK({0}) - 1)"), // from Sunday as 0 instead of 1
+	"YearDay": internal.DatePartFunctionDescriptor("DAYOFYEAR({0})"),
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) operatorOverrides() map[string]internal.BinaryOperatorDescriptor {
+	return mysqlBinaryOperators
+}
+
+func (mysqlDialect) caseInsensitiveOperators() map[string]internal.BinaryOperatorDescriptor {
+	return mysqlCaseInsensitiveBinaryOperators
+}
+
+func (mysqlDialect) functionOverrides() map[string]internal.FunctionDescriptor {
+	return mysqlFunctions
+}
+
+func (mysqlDialect) dateParts() map[string]internal.FunctionDescriptor {
+	return mysqlDateParts
+}
+
+func (mysqlDialect) identifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+var mysqlPrimitiveTypeCast = map[internal.ExprType]string{
+	internal.ExprTypeIntIdentifier:   "SIGNED",
+	internal.ExprTypeFloatIdentifier: "DOUBLE",
+}
+
+func (d mysqlDialect) jsonExpr(column string, path []any, exprType internal.ExprType) string {
+	extract := fmt.Sprintf("JSON_EXTRACT(%v, %v)", column, d.quote(jsonPathExpression(path)))
+	if exprType == internal.ExprTypeStringIdentifier {
+		return fmt.Sprintf("JSON_UNQUOTE(%v)", extract)
+	}
+	if exprType == internal.ExprTypeBoolIdentifier {
+		return fmt.Sprintf("(%v = CAST('true' AS JSON))", extract)
+	}
+	if t, ok := mysqlPrimitiveTypeCast[exprType]; ok {
+		return fmt.Sprintf("CAST(%v AS %v)", extract, t)
+	}
+	return extract
+}
+
+// jsonTimestamp adds epoch timestamps to the epoch instead of using FROM_UNIXTIME, which depends on the session time zone
+func (mysqlDialect) jsonTimestamp(value string, format JSONTimestampFormat) string {
+	switch format {
+	case JSONTimestampFormatEpochSeconds:
+		return fmt.Sprintf("TIMESTAMPADD(MICROSECOND, CAST(%v * 1000000 AS SIGNED), '1970-01-01 00:00:00')", value)
+	case JSONTimestampFormatEpochMillis:
+		return fmt.Sprintf("TIMESTAMPADD(MICROSECOND, CAST(%v * 1000 AS SIGNED), '1970-01-01 00:00:00')", value)
+	default:
+		return fmt.Sprintf("CAST(%v AS DATETIME(6))", value)
+	}
+}
+
+var mysqlArrayElementTypes = map[internal.ExprType]string{
+	internal.ExprTypeIntIdentifier:       "BIGINT",
+	internal.ExprTypeFloatIdentifier:     "DOUBLE",
+	internal.ExprTypeBoolIdentifier:      "BOOLEAN",
+	internal.ExprTypeStringIdentifier:    "LONGTEXT",
+	internal.ExprTypeTimestampIdentifier: "DATETIME(6)",
+}
+
+func (mysqlDialect) jsonCondition(internal.TranslationResult, []any, string, any) (internal.TranslationResult, bool) {
+	return internal.TranslationResult{}, false
+}
+
+func (mysqlDialect) arrayElements(array string, elemType internal.ExprType, alias string) (string, string) {
+	return fmt.Sprintf("JSON_TABLE(%v, '$[*]' COLUMNS (value %v PATH '$')) AS %v", array, mysqlArrayElementTypes[elemType], alias), alias + ".value"
+}
+
+func (mysqlDialect) arrayAggregate(elem string) string {
+	return fmt.Sprintf("COALESCE(JSON_ARRAYAGG(%v), JSON_ARRAY())", elem)
+}
+
+// jsonArrayElements expands elements of other than scalar types as JSON values
+func (d mysqlDialect) jsonArrayElements(array string, elemType internal.ExprType, alias string) (string, string) {
+	if _, ok := mysqlArrayElementTypes[elemType]; !ok {
+		return fmt.Sprintf("JSON_TABLE(%v, '$[*]' COLUMNS (value JSON PATH '$')) AS %v", array, alias), alias + ".value"
+	}
+	return d.arrayElements(array, elemType, alias)
+}
+
+func (d mysqlDialect) jsonArrayAggregate(elem string) string {
+	return d.arrayAggregate(elem)
+}
+
+func (d mysqlDialect) quote(s string) string {
+	return internal.EscapePlaceholders(d.literal(s))
+}
+
+// orderBy sorts by whether the key is NULL first, as MySQL lacks NULLS FIRST and NULLS LAST
+func (mysqlDialect) orderBy(desc bool, nulls SortNulls) string {
+	switch nulls {
+	case SortNullsFirst:
+		return "{0} IS NULL DESC, " + standardOrderBy(desc, SortNullsDefault)
+	case SortNullsLast:
+		return "{0} IS NULL ASC, " + standardOrderBy(desc, SortNullsDefault)
+	default:
+		return standardOrderBy(desc, nulls)
+	}
+}
+
+func (d mysqlDialect) literal(value any) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf(`'%v'`, strings.NewReplacer(`\`, `\\`, "'", "''").Replace(v))
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'G', -1, 64)
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v))
+	case time.Time:
+		return d.literal(v.Format("2006-01-02 15:04:05.999999"))
+	default:
+		return d.literal(fmt.Sprint(v))
+	}
+}
+
+func (mysqlDialect) placeholder(int) string {
+	return "?"
+}
@@ -0,0 +1,274 @@
+package filter_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/happening-oss/expr2sql/pkg/filter"
+)
+
+var _ = Describe("MySQL translator", func() {
+	var trs filter.Translator
+
+	BeforeEach(func() {
+		trs = newTranslator([]filter.Identifier{
+			{ExprName: "intField", Type: filter.IdentifierTypeInt},
+			{ExprName: "floatField", Type: filter.IdentifierTypeFloat},
+			{ExprName: "boolField", Type: filter.IdentifierTypeBool},
+			{ExprName: "stringField", Type: filter.IdentifierTypeString, DBName: "string_field"},
+			{ExprName: "tsField", Type: filter.IdentifierTypeTimestamp},
+			{ExprName: "jsonField", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+				"nestedProperty1": filter.JSONTree{
+					"nested property 2": filter.JSONTree{
+						"stringProperty": filter.JSONLeaf(filter.IdentifierTypeString),
+					},
+				},
+				"intProperty":    filter.JSONLeaf(filter.IdentifierTypeInt),
+				"floatProperty":  filter.JSONLeaf(filter.IdentifierTypeFloat),
+				"boolProperty":   filter.JSONLeaf(filter.IdentifierTypeBool),
+				"stringProperty": filter.JSONLeaf(filter.IdentifierTypeString),
+			}},
+		}, filter.TranslatorDialectMySQL)
+	})
+
+	Describe("identifier translation", func() {
+		It("quotes identifiers", func() {
+			query, err := trs.Translate("intField == 2 and boolField")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((`intField` = 2) and `boolField`)")))
+		})
+
+		It("quotes db names and escapes strings", func() {
+			query, err := trs.Translate(`stringField == "ab'c\\d"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(`string_field` = 'ab''c\\\\d')")))
+		})
+
+		It("quotes qualified db names", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "order", DBName: "Order", Table: "o", Type: filter.IdentifierTypeInt},
+			}, filter.TranslatorDialectMySQL)
+
+			query, err := trs.Translate("order > 2")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(`o`.`Order` > 2)")))
+		})
+
+		It("translates nil", func() {
+			query, err := trs.Translate("floatField != nil")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(`floatField` IS NOT NULL)")))
+		})
+
+		It("translates timestamp", func() {
+			query, err := trs.Translate(`tsField < "2024-09-17T08:00:01.2345+03:00"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(`tsField` < '2024-09-17 05:00:01.2345')")))
+		})
+	})
+
+	Describe("json translation", func() {
+		It("translates string", func() {
+			query, err := trs.Translate(`jsonField.stringProperty == "abcd"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(JSON_UNQUOTE(JSON_EXTRACT(`jsonField`, '$.stringProperty')) = 'abcd')")))
+		})
+
+		It("translates nested property", func() {
+			query, err := trs.Translate(`jsonField.nestedProperty1["nested property 2"].stringProperty == nil`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`(JSON_UNQUOTE(JSON_EXTRACT(` + "`jsonField`" + `, '$.nestedProperty1."nested property 2".stringProperty')) IS NULL)`)))
+		})
+
+		It("translates timestamps", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "event", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+					"at":     filter.JSONLeaf(filter.IdentifierTypeTimestamp),
+					"sentAt": filter.JSONTimestamp(filter.JSONTimestampFormatEpochSeconds),
+				}},
+			}, filter.TranslatorDialectMySQL)
+
+			query, err := trs.Translate(`event.at > "2024-09-17T08:00:00Z" and event.sentAt < event.at`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((CAST(JSON_UNQUOTE(JSON_EXTRACT(`event`, '$.at')) AS DATETIME(6)) > '2024-09-17 08:00:00') and (TIMESTAMPADD(MICROSECOND, CAST(CAST(JSON_EXTRACT(`event`, '$.sentAt') AS DOUBLE) * 1000000 AS SIGNED), '1970-01-01 00:00:00') < CAST(JSON_UNQUOTE(JSON_EXTRACT(`event`, '$.at')) AS DATETIME(6))))")))
+		})
+
+		It("translates other primitive types", func() {
+			query, err := trs.Translate("jsonField.intProperty <= 2 and jsonField.floatProperty > 1.5 or jsonField.boolProperty")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((CAST(JSON_EXTRACT(`jsonField`, '$.intProperty') AS SIGNED) <= 2) and (CAST(JSON_EXTRACT(`jsonField`, '$.floatProperty') AS DOUBLE) > 1.5)) or (JSON_EXTRACT(`jsonField`, '$.boolProperty') = CAST('true' AS JSON)))")))
+		})
+	})
+
+	Describe("expressions", func() {
+		It("translates exponent", func() {
+			query, err := trs.Translate(`floatField > floatField ** 2 or intField == intField ^ 2`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((`floatField` > (POW(`floatField`, 2))) or (`intField` = (POW(`intField`, 2))))")))
+		})
+
+		It("translates string expressions", func() {
+			query, err := trs.Translate(`stringField startsWith "abcd" and jsonField.stringProperty matches "[A-Z]+"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((`string_field` like 'abcd%') and (JSON_UNQUOTE(JSON_EXTRACT(`jsonField`, '$.stringProperty')) REGEXP '[A-Z]+'))")))
+		})
+
+		It("escapes like wildcards", func() {
+			query, err := trs.Translate(`stringField contains "50%_"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(`string_field` like '%50\\\\%\\\\_%')")))
+		})
+
+		It("translates functions", func() {
+			query, err := trs.Translate(`len(stringField) > 3 and tsField < now() and date(jsonField.stringProperty) > date("2025-01-01")`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((CHAR_LENGTH(`string_field`) > 3) and (`tsField` < UTC_TIMESTAMP(6))) and (CAST(JSON_UNQUOTE(JSON_EXTRACT(`jsonField`, '$.stringProperty')) AS DATETIME(6)) > '2025-01-01 00:00:00'))")))
+		})
+
+		It("truncates timestamps to the day", func() {
+			query, err := trs.Translate(`date(tsField) == date("2025-01-01")`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(CAST(DATE(`tsField`) AS DATETIME(6)) = '2025-01-01 00:00:00')")))
+		})
+
+		It("translates timestamp and duration arithmetic", func() {
+			query, err := trs.Translate(`tsField > now() - duration("-24h") and tsField - date(jsonField.stringProperty) < duration("1ms") and duration("1s") + tsField < now()`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((`tsField` > (TIMESTAMPADD(MICROSECOND, -(-86400000000), UTC_TIMESTAMP(6)))) and ((TIMESTAMPDIFF(MICROSECOND, CAST(JSON_UNQUOTE(JSON_EXTRACT(`jsonField`, '$.stringProperty')) AS DATETIME(6)), `tsField`)) < 1000)) and ((TIMESTAMPADD(MICROSECOND, 1000000, `tsField`)) < UTC_TIMESTAMP(6)))")))
+		})
+
+		It("translates date part accessors", func() {
+			query, err := trs.Translate(`tsField.Month() == 12 and tsField.Weekday() * 2 > 4`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((MONTH(`tsField`) = 12) and (((DAYOFWEEK(`tsField`) - 1) * 2) > 4))")))
+		})
+
+		It("translates array expressions", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "tags", Type: filter.IdentifierTypeArray(filter.IdentifierTypeString)},
+				{ExprName: "scores", Type: filter.IdentifierTypeArray(filter.IdentifierTypeInt)},
+			}, filter.TranslatorDialectMySQL)
+
+			query, err := trs.Translate(`"vip" in tags and any(scores, # > 50) and len(filter(tags, # != "x")) > 1`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((('vip' MEMBER OF(`tags`)) and (EXISTS (SELECT 1 FROM JSON_TABLE(`scores`, '$[*]' COLUMNS (value BIGINT PATH '$')) AS elem1 WHERE (elem1.value > 50)))) and (JSON_LENGTH((SELECT COALESCE(JSON_ARRAYAGG(elem1.value), JSON_ARRAY()) FROM JSON_TABLE(`tags`, '$[*]' COLUMNS (value LONGTEXT PATH '$')) AS elem1 WHERE (elem1.value <> 'x'))) > 1))")))
+		})
+
+		It("translates json array expressions", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "order", DBName: "order_data", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+					"items": filter.JSONArray(filter.JSONTree{"qty": filter.JSONLeaf(filter.IdentifierTypeInt)}),
+					"tags":  filter.JSONArray(filter.JSONLeaf(filter.IdentifierTypeString)),
+				}},
+			}, filter.TranslatorDialectMySQL)
+
+			query, err := trs.Translate(`order.items[0].qty > 1 and any(order.items, .qty > 3) and "gift" in order.tags`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((CAST(JSON_EXTRACT(`order_data`, '$.items[0].qty') AS SIGNED) > 1) and (EXISTS (SELECT 1 FROM JSON_TABLE(JSON_EXTRACT(`order_data`, '$.items'), '$[*]' COLUMNS (value JSON PATH '$')) AS elem1 WHERE (CAST(JSON_EXTRACT(elem1.value, '$.qty') AS SIGNED) > 3)))) and ('gift' MEMBER OF(JSON_EXTRACT(`order_data`, '$.tags'))))")))
+		})
+
+		It("translates dynamic json expressions", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "attrs", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONDynamic(nil)},
+			}, filter.TranslatorDialectMySQL)
+
+			query, err := trs.Translate(`attrs.size > 3 and attrs["first name"] == "x" and bool(attrs.dims.flat)`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((CAST(JSON_EXTRACT(`attrs`, '$.size') AS DOUBLE) > 3) and (JSON_UNQUOTE(JSON_EXTRACT(`attrs`, '$.\"first name\"')) = 'x')) and (JSON_EXTRACT(`attrs`, '$.dims.flat') = CAST('true' AS JSON)))")))
+		})
+
+		It("ignores json containment", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "attrs", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONDynamic(nil)},
+			}, filter.TranslatorDialectMySQL, filter.WithJSONContainment())
+
+			query, err := trs.Translate(`attrs.size == 3`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(CAST(JSON_EXTRACT(`attrs`, '$.size') AS DOUBLE) = 3)")))
+		})
+
+		It("translates order by with nulls positions", func() {
+			orderBy, err := trs.TranslateOrderBy("intField desc nulls last, jsonField.stringProperty nulls first")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(orderBy).To(Equal(filter.SQLOrderBy("`intField` IS NULL ASC, `intField` DESC, JSON_UNQUOTE(JSON_EXTRACT(`jsonField`, '$.stringProperty')) IS NULL DESC, JSON_UNQUOTE(JSON_EXTRACT(`jsonField`, '$.stringProperty')) ASC")))
+		})
+
+		It("translates relations", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "customer", DBName: "orders.customer_id", Type: filter.IdentifierTypeRelation, Relation: &filter.Relation{
+					Table: "customers", Column: "id", Identifiers: []filter.Identifier{{ExprName: "country", Type: filter.IdentifierTypeString}},
+				}},
+				{ExprName: "lineItems", DBName: "orders.id", Type: filter.IdentifierTypeRelation, Relation: &filter.Relation{
+					Table: "line_items", Column: "order_id", Many: true, Identifiers: []filter.Identifier{{ExprName: "sku", Type: filter.IdentifierTypeString}},
+				}},
+			}, filter.TranslatorDialectMySQL)
+
+			query, err := trs.Translate(`customer.country == "DE" and none(lineItems, .sku == "X")`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((SELECT `rel1`.`country` FROM `customers` AS `rel1` WHERE `rel1`.`id` = `orders`.`customer_id`) = 'DE') and " +
+				"(NOT EXISTS (SELECT 1 FROM `line_items` AS `rel1` WHERE `rel1`.`order_id` = `orders`.`id` AND (`rel1`.`sku` = 'X'))))")))
+		})
+
+		It("translates having with grouping keys", func() {
+			query, err := trs.TranslateHaving(`jsonField.stringProperty == "x" and avg(intField) > 1.5`, "jsonField.stringProperty")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLHavingCondition("((JSON_UNQUOTE(JSON_EXTRACT(`jsonField`, '$.stringProperty')) = 'x') and (avg(`intField`) > 1.5))")))
+		})
+
+		It("translates case-insensitive expressions", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "name", Type: filter.IdentifierTypeString},
+			}, filter.TranslatorDialectMySQL, filter.WithCaseInsensitiveStrings())
+
+			query, err := trs.Translate(`name contains "acme" or name == "ACME"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((lower(`name`) like lower('%acme%')) or (lower(`name`) = lower('ACME')))")))
+		})
+
+		It("translates simplified expressions", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "intField", Type: filter.IdentifierTypeInt},
+				{ExprName: "boolField", Type: filter.IdentifierTypeBool},
+			}, filter.TranslatorDialectMySQL, filter.WithSimplifiedSQL())
+
+			query, err := trs.Translate(`(intField > 2 * 3 or not boolField) and intField ** 2 < 100 and (true or intField == 1)`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(`intField` > 6 or not `boolField`) and (POW(`intField`, 2)) < 100")))
+		})
+
+		It("binds parameters", func() {
+			query, args, err := trs.TranslateParams(`intField > 2 and tsField <= "2024-09-17T08:00:00Z"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((`intField` > ?) and (`tsField` <= ?))")))
+			Expect(args).To(Equal([]any{2, time.Date(2024, 9, 17, 8, 0, 0, 0, time.UTC)}))
+		})
+	})
+})
@@ -1,155 +1,155 @@
 package filter
 
 import (
+	"encoding/json"
 	"fmt"
+	"regexp"
 	"slices"
 	"strconv"
 	"strings"
 	"time"
 
-	"github.com/expr-lang/expr/ast"
-	"github.com/expr-lang/expr/parser"
-
 	"github.com/happening-oss/expr2sql/pkg/filter/internal"
 )
 
-var unaryOperators = map[string]internal.UnaryOperatorDescriptor{
-	"!":   internal.UnaryBooleanOperatorDescriptor("not"),
-	"not": internal.UnaryBooleanOperatorDescriptor("not"),
-	"-":   internal.UnaryNumericOperatorDescriptor("-"),
-}
+var postgresBinaryOperators = map[string]internal.BinaryOperatorDescriptor{
+	"in": internal.CombinedOperatorDescriptor(
+		internal.MembershipOperatorDescriptor("IN", false),
+		internal.ArrayMembershipOperatorDescriptor("{0} = ANY({1})"),
+		postgresJSONArrayMembership("{1} @> jsonb_build_array({0})"),
+	),
+	"not in": internal.CombinedOperatorDescriptor(
+		internal.MembershipOperatorDescriptor("NOT IN", true),
+		internal.ArrayMembershipOperatorDescriptor("NOT ({0} = ANY({1}))"),
+		postgresJSONArrayMembership("NOT ({1} @> jsonb_build_array({0}))"),
+	),
+
+	"+": internal.CombinedOperatorDescriptor(internal.NumericOperatorDescriptor("+"), internal.TemporalOperatorDescriptor("+", postgresTemporal("+"))),
+	"-": internal.CombinedOperatorDescriptor(internal.NumericOperatorDescriptor("-"), internal.TemporalOperatorDescriptor("-", postgresTemporal("-"))),
 
-var binaryOperators = map[string]internal.BinaryOperatorDescriptor{
-	"+":  internal.NumericOperatorDescriptor("+"),
-	"-":  internal.NumericOperatorDescriptor("-"),
-	"*":  internal.NumericOperatorDescriptor("*"),
-	"/":  internal.NumericOperatorDescriptor("/"),
-	"%":  internal.NumericOperatorDescriptor("%"),
 	"**": internal.NumericOperatorDescriptor("^"),
 	"^":  internal.NumericOperatorDescriptor("^"),
 
-	"==": internal.NillableComparisonOperatorDescriptor("=", "IS"),
-	"!=": internal.NillableComparisonOperatorDescriptor("<>", "IS NOT"),
-	"<":  internal.ComparisonOperatorDescriptor("<"),
-	">":  internal.ComparisonOperatorDescriptor(">"),
-	"<=": internal.ComparisonOperatorDescriptor("<="),
-	">=": internal.ComparisonOperatorDescriptor(">="),
-
-	"&&":  internal.BooleanOperatorDescriptor("and"),
-	"and": internal.BooleanOperatorDescriptor("and"),
-	"||":  internal.BooleanOperatorDescriptor("or"),
-	"or":  internal.BooleanOperatorDescriptor("or"),
+	"matches": internal.RegexOperatorDescriptor("~"),
+}
 
-	"contains":   internal.StringLikeOperatorDescriptor("%%", "%%"),
-	"startsWith": internal.StringLikeOperatorDescriptor("", "%%"),
-	"endsWith":   internal.StringLikeOperatorDescriptor("%%", ""),
-	"matches": {
-		TypeConstraints: []internal.BinaryOperatorTypeConstraint{
-			{Left: internal.ExprTypeStringIdentifier, Right: internal.ExprTypeString},
-		},
-		OpTranslator: func(left, right internal.TranslationResult) internal.TranslationResult {
-			return internal.TranslationResult{
-				Expr: fmt.Sprintf("%v ~ %v", left.Expr, right.Expr),
-				Type: internal.ExprTypeBool,
-			}
-		},
-	},
+// postgresTemporal adds and subtracts timestamps and intervals, casting timestamp literals
+// which are ambiguous next to an interval
+func postgresTemporal(op string) func(left, right internal.TranslationResult) internal.TranslationResult {
+	return func(left, right internal.TranslationResult) internal.TranslationResult {
+		return internal.FormatTemplate("{0} "+op+" {1}", postgresTimestamp(left), postgresTimestamp(right))
+	}
 }
 
-type postgresTranslator struct {
-	allowedIdentifiers []Identifier
+func postgresTimestamp(result internal.TranslationResult) internal.TranslationResult {
+	if result.Type == internal.ExprTypeTimestamp && internal.IsLiteral(result) {
+		result.Expr = fmt.Sprintf("cast(%v as timestamptz)", result.Expr)
+	}
+	return result
 }
 
-func newPostgresTranslator(allowedIdentifiers []Identifier) *postgresTranslator {
-	return &postgresTranslator{allowedIdentifiers}
+var postgresJSONValueTypes = map[internal.ExprType]string{
+	internal.ExprTypeIntIdentifier:    "bigint",
+	internal.ExprTypeFloatIdentifier:  "double precision",
+	internal.ExprTypeStringIdentifier: "text",
 }
 
-func (t *postgresTranslator) Translate(query string) (SQLWhereCondition, error) {
-	parsed, err := parser.Parse(query)
-	if err != nil {
-		return "", &ParsingError{err}
+// postgresJSONArrayMembership checks containment of the value in the JSON array, casting the value
+// to the type of the elements as jsonb_build_array cannot infer the type of literals
+func postgresJSONArrayMembership(template string) internal.BinaryOperatorDescriptor {
+	descriptor := internal.JSONArrayMembershipOperatorDescriptor(template)
+	opTranslator := descriptor.OpTranslator
+	descriptor.OpTranslator = func(left, right internal.TranslationResult) internal.TranslationResult {
+		elemType, _ := internal.JSONElemType(right.Type)
+		left.Expr = fmt.Sprintf("cast(%v as %v)", left.Expr, postgresJSONValueTypes[elemType])
+		return opTranslator(left, right)
 	}
-	result, err := t.translate(parsed.Node)
-	if err != nil {
-		return "", err
-	}
-	if result.Type != internal.ExprTypeBool && result.Type != internal.ExprTypeBoolIdentifier {
-		return "", ErrInvalidFilter
-	}
-	return SQLWhereCondition(result.Expr), nil
-}
-
-func (t *postgresTranslator) translate(node ast.Node) (translated internal.TranslationResult, err error) {
-	switch typed := node.(type) {
-	case *ast.NilNode:
-		return internal.TranslationResult{Expr: "NULL", Type: internal.ExprTypeNil}, nil
-	case *ast.IdentifierNode:
-		translated, _, err = t.translateIdentifier(typed)
-		return translated, err
-	case *ast.StringNode:
-		exprType := internal.ExprTypeString
-		t, err := time.Parse(time.RFC3339Nano, typed.Value) // special case for timestamp strings
-		if err == nil {
-			typed.Value = t.UTC().Format(time.RFC3339Nano) // adjust valid timestamp to UTC in case DB column does not use time zones
-			exprType = internal.ExprTypeTimestamp
-		}
-		typed.Value = strings.ReplaceAll(typed.Value, "'", "''")
-		return internal.TranslationResult{Expr: fmt.Sprintf(`'%v'`, typed.Value), Type: exprType}, nil
-	case *ast.IntegerNode:
-		return internal.TranslationResult{Expr: strconv.Itoa(typed.Value), Type: internal.ExprTypeInt}, nil
-	case *ast.FloatNode:
-		return internal.TranslationResult{Expr: strconv.FormatFloat(typed.Value, 'G', -1, 64), Type: internal.ExprTypeFloat}, nil
-	case *ast.BoolNode:
-		return internal.TranslationResult{Expr: strings.ToUpper(strconv.FormatBool(typed.Value)), Type: internal.ExprTypeBool}, nil
-	case *ast.BinaryNode:
-		leftExpr, err := t.translate(typed.Left)
-		if err != nil {
-			return internal.TranslationResult{}, err
-		}
-		rightExpr, err := t.translate(typed.Right)
-		if err != nil {
-			return internal.TranslationResult{}, err
-		}
-		return t.translateBinaryOperator(typed.Operator, leftExpr, rightExpr)
-	case *ast.UnaryNode:
-		expr, err := t.translate(typed.Node)
-		if err != nil {
-			return internal.TranslationResult{}, err
-		}
-		return t.translateUnaryOperator(typed.Operator, expr)
-	case *ast.MemberNode:
-		translated, _, err = t.translateJSON(typed)
-		return translated, err
-	default:
-		return internal.TranslationResult{}, unsupportedOperation(fmt.Sprintf("%v", node))
+	return descriptor
+}
+
+var postgresCaseInsensitiveBinaryOperators = map[string]internal.BinaryOperatorDescriptor{
+	"contains":   internal.LikeOperatorDescriptor("%v ilike %v", "%", "%"),
+	"startsWith": internal.LikeOperatorDescriptor("%v ilike %v", "", "%"),
+	"endsWith":   internal.LikeOperatorDescriptor("%v ilike %v", "%", ""),
+}
+
+var postgresFunctions = map[string]internal.FunctionDescriptor{
+	"len": internal.CombinedFunctionDescriptor(
+		internal.LengthFunctionDescriptor("length({0})"),
+		internal.ArrayLengthFunctionDescriptor("cardinality({0})"),
+		internal.JSONArrayLengthFunctionDescriptor("jsonb_array_length({0})"),
+	),
+	// ceil, floor and round result in double precision or numeric for integers
+	"ceil":  internal.IntegerNumericFunctionDescriptor("ceil({0})", "cast({0} as bigint)"),
+	"floor": internal.IntegerNumericFunctionDescriptor("floor({0})", "cast({0} as bigint)"),
+	"round": internal.IntegerNumericFunctionDescriptor("round({0})", "cast({0} as bigint)"),
+	"date":  internal.DateFunctionDescriptor("cast({0} as timestamptz)", "date_trunc('day', {0})"),
+	"duration": internal.DurationFunctionDescriptor(func(d time.Duration) internal.TranslationResult {
+		return internal.TranslationResult{Expr: "make_interval(secs => ?)", Args: []any{d.Seconds()}}
+	}),
+}
+
+var postgresDateParts = map[string]internal.FunctionDescriptor{
+	"Year":    postgresDatePart("extract(year from {0})"),
+	"Month":   postgresDatePart("extract(month from {0})"),
+	"Day":     postgresDatePart("extract(day from {0})"),
+	"Hour":    postgresDatePart("extract(hour from {0})"),
+	"Minute":  postgresDatePart("extract(minute from {0})"),
+	"Second":  postgresDatePart("floor(extract(second from {0}))"), // without the fraction
+	"Weekday": postgresDatePart("extract(dow from {0})"),
+	"YearDay": postgresDatePart("extract(doy from {0})"),
+}
+
+// postgresDatePart casts the extracted numeric to an integer, and timestamp literals to timestamps
+func postgresDatePart(template string) internal.FunctionDescriptor {
+	descriptor := internal.DatePartFunctionDescriptor("cast(" + template + " as integer)")
+	fnTranslator := descriptor.FnTranslator
+	descriptor.FnTranslator = func(args []internal.TranslationResult) (internal.TranslationResult, error) {
+		return fnTranslator([]internal.TranslationResult{postgresTimestamp(args[0])})
 	}
+	return descriptor
 }
 
-func (t *postgresTranslator) translateJSON(node ast.Node) (internal.TranslationResult, JSONElement, error) {
-	switch typed := node.(type) {
-	case *ast.IdentifierNode:
-		return t.translateIdentifier(typed)
-	case *ast.MemberNode:
-		name, jsonEl, err := t.translateJSON(typed.Node)
-		if err != nil {
-			return internal.TranslationResult{}, nil, err
-		}
-		property, ok := typed.Property.(*ast.StringNode)
-		if !ok {
-			return internal.TranslationResult{}, nil, unsupportedOperation(fmt.Sprintf("json key needs to be string, instead found %v", typed.Property))
-		}
-		if _, ok := jsonEl.(JSONTree); !ok || jsonEl.IdentifierType() != IdentifierTypeJSON || name.Type != internal.ExprTypeJSONIdentifier {
-			return internal.TranslationResult{}, nil, unsupportedOperation(fmt.Sprintf("value at '%v' is not a json object", typed.Node))
-		}
-		jsonEl, ok = jsonEl.(JSONTree)[property.Value]
-		if !ok {
-			return internal.TranslationResult{}, nil, unknownIdentifier(fmt.Sprintf("json object at '%v' does not contain field '%v'", typed.Node, property.Value))
-		}
-		expr := typedJSONExpr(name.Expr, property.Value, internal.ExprType(jsonEl.IdentifierType()))
-		return internal.TranslationResult{Expr: expr, Type: internal.ExprType(jsonEl.IdentifierType())}, jsonEl, nil
-	default:
-		return internal.TranslationResult{}, nil, unsupportedOperation(fmt.Sprintf("json %v", node))
+type postgresDialect struct {
+	caseSensitiveNames bool // quotes names containing upper case letters, instead of letting them be folded
+}
+
+func (postgresDialect) operatorOverrides() map[string]internal.BinaryOperatorDescriptor {
+	return postgresBinaryOperators
+}
+
+func (postgresDialect) caseInsensitiveOperators() map[string]internal.BinaryOperatorDescriptor {
+	return postgresCaseInsensitiveBinaryOperators
+}
+
+func (postgresDialect) functionOverrides() map[string]internal.FunctionDescriptor {
+	return postgresFunctions
+}
+
+func (postgresDialect) dateParts() map[string]internal.FunctionDescriptor {
+	return postgresDateParts
+}
+
+// postgresPlainIdentifier matches names which need no quotes, which are folded to lower case
+var postgresPlainIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// postgresReservedKeywords cannot be column names without quotes
+var postgresReservedKeywords = strings.Fields(`all analyse analyze and any array as asc asymmetric authorization binary both
+	case cast check collate collation column concurrently constraint create cross current_catalog current_date current_role
+	current_schema current_time current_timestamp current_user default deferrable desc distinct do else end except false
+	fetch for foreign freeze from full grant group having ilike in initially inner intersect into is isnull join lateral
+	leading left like limit localtime localtimestamp natural not notnull null offset on only or order outer overlaps
+	placing primary references returning right select session_user similar some symmetric system_user table tablesample
+	then to trailing true union unique user using variadic verbose when where window with`)
+
+// identifier quotes names which are reserved keywords or contain special characters, and with caseSensitiveNames
+// names which contain upper case letters
+func (d postgresDialect) identifier(name string) string {
+	caseFolded := d.caseSensitiveNames && strings.ToLower(name) != name
+	if postgresPlainIdentifier.MatchString(name) && !caseFolded && !slices.Contains(postgresReservedKeywords, strings.ToLower(name)) {
+		return name
 	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
 }
 
 var primitiveTypeCast = map[internal.ExprType]string{
@@ -158,49 +158,117 @@ var primitiveTypeCast = map[internal.ExprType]string{
 	internal.ExprTypeBoolIdentifier:  "boolean",
 }
 
-func typedJSONExpr(object, key string, exprType internal.ExprType) string {
-	if exprType == internal.ExprTypeStringIdentifier || exprType == internal.ExprTypeTimestampIdentifier {
-		return fmt.Sprintf("%v ->> '%v'", object, key)
+func (d postgresDialect) jsonExpr(column string, path []any, exprType internal.ExprType) string {
+	object := column
+	for _, key := range path[:len(path)-1] {
+		object = fmt.Sprintf("%v -> %v", object, d.jsonKey(key))
+	}
+	key := d.jsonKey(path[len(path)-1])
+	if exprType == internal.ExprTypeStringIdentifier {
+		return fmt.Sprintf("%v ->> %v", object, key)
 	}
 	if t, ok := primitiveTypeCast[exprType]; ok {
-		return fmt.Sprintf("cast(%v ->> '%v' as %v)", object, key, t)
+		return fmt.Sprintf("cast(%v ->> %v as %v)", object, key, t)
+	}
+	return fmt.Sprintf("%v -> %v", object, key)
+}
+
+func (postgresDialect) jsonTimestamp(value string, format JSONTimestampFormat) string {
+	switch format {
+	case JSONTimestampFormatEpochSeconds:
+		return fmt.Sprintf("to_timestamp(%v)", value)
+	case JSONTimestampFormatEpochMillis:
+		return fmt.Sprintf("to_timestamp(%v / 1000)", value)
+	default:
+		return fmt.Sprintf("cast(%v as timestamptz)", value)
+	}
+}
+
+// jsonCondition translates equality of object properties to containment of a JSON document, e.g.
+// data @> '{"a":{"b":"x"}}', and other comparisons to JSONPath filters, e.g. data @? '$.a.b ? (@ > 3)'
+func (postgresDialect) jsonCondition(column internal.TranslationResult, path []any, op string, value any) (internal.TranslationResult, bool) {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return internal.TranslationResult{}, false
+	}
+	if document, ok := postgresJSONDocument(path, jsonValue); ok && op == "==" {
+		return internal.FormatTemplate("{0} @> {1}", column, internal.Literal(document, internal.ExprTypeString)), true
+	}
+	filter := fmt.Sprintf("%v ? (@ %v %s)", jsonPathExpression(path), op, jsonValue)
+	return internal.FormatTemplate("{0} @? {1}", column, internal.Literal(filter, internal.ExprTypeString)), true
+}
+
+// postgresJSONDocument nests the JSON value within the objects of the path, which cannot contain array indices
+// as containment of arrays disregards the position of elements
+func postgresJSONDocument(path []any, jsonValue []byte) (string, bool) {
+	var document json.RawMessage = jsonValue
+	for i := len(path) - 1; i >= 0; i-- {
+		key, ok := path[i].(string)
+		if !ok {
+			return "", false
+		}
+		document, _ = json.Marshal(map[string]json.RawMessage{key: document})
 	}
-	return fmt.Sprintf("%v -> '%v'", object, key)
+	return string(document), true
 }
 
-func (t *postgresTranslator) translateIdentifier(node *ast.IdentifierNode) (translated internal.TranslationResult, jsonEl JSONElement, err error) {
-	index := slices.IndexFunc(t.allowedIdentifiers, func(id Identifier) bool {
-		return id.ExprName == node.Value
-	})
-	if index == -1 {
-		return internal.TranslationResult{}, nil, unknownIdentifier(node.Value)
+func (postgresDialect) arrayElements(array string, _ internal.ExprType, alias string) (string, string) {
+	return fmt.Sprintf("unnest(%v) AS %v", array, alias), alias
+}
+
+func (postgresDialect) arrayAggregate(elem string) string {
+	return fmt.Sprintf("coalesce(array_agg(%v), '{}')", elem)
+}
+
+// jsonArrayElements expands scalar elements as text, cast like the properties of jsonExpr
+func (postgresDialect) jsonArrayElements(array string, elemType internal.ExprType, alias string) (string, string) {
+	if !slices.Contains(internal.ScalarIdentifierTypes, elemType) {
+		return fmt.Sprintf("jsonb_array_elements(%v) AS %v", array, alias), alias
 	}
-	name := node.Value
-	identifier := t.allowedIdentifiers[index]
-	if identifier.DBName != "" {
-		name = identifier.DBName
+	elem := alias
+	if t, ok := primitiveTypeCast[elemType]; ok {
+		elem = fmt.Sprintf("cast(%v as %v)", alias, t)
 	}
-	return internal.TranslationResult{Expr: name, Type: internal.ExprType(identifier.Type)}, identifier.JSONSpec, nil
+	return fmt.Sprintf("jsonb_array_elements_text(%v) AS %v", array, alias), elem
 }
 
-func (t *postgresTranslator) translateBinaryOperator(op string, leftExpr, rightExpr internal.TranslationResult) (internal.TranslationResult, error) {
-	descriptor, ok := binaryOperators[op]
-	if !ok ||
-		len(descriptor.TypeConstraints) > 0 &&
-			!slices.Contains(descriptor.TypeConstraints, internal.BinaryOperatorTypeConstraint{Left: leftExpr.Type, Right: rightExpr.Type}) {
-		return internal.TranslationResult{}, unsupportedOperation(fmt.Sprintf("%v %v %v", leftExpr.Expr, op, rightExpr.Expr))
+func (postgresDialect) jsonArrayAggregate(elem string) string {
+	return fmt.Sprintf("coalesce(jsonb_agg(%v), '[]')", elem)
+}
+
+// jsonKey formats the key of an object or the index of an array
+func (d postgresDialect) jsonKey(key any) string {
+	if index, ok := key.(int); ok {
+		return strconv.Itoa(index)
 	}
-	result := descriptor.OpTranslator(leftExpr, rightExpr)
-	result.Expr = fmt.Sprintf("(%v)", result.Expr)
-	return result, nil
+	return d.quote(key.(string))
+}
+
+func (d postgresDialect) quote(s string) string {
+	return internal.EscapePlaceholders(d.literal(s))
+}
+
+func (postgresDialect) orderBy(desc bool, nulls SortNulls) string {
+	return standardOrderBy(desc, nulls)
 }
 
-func (t *postgresTranslator) translateUnaryOperator(op string, expr internal.TranslationResult) (internal.TranslationResult, error) {
-	descriptor, ok := unaryOperators[op]
-	if !ok || len(descriptor.TypeConstraints) > 0 && !slices.Contains(descriptor.TypeConstraints, expr.Type) {
-		return internal.TranslationResult{}, unsupportedOperation(fmt.Sprintf("%v%v", op, expr.Expr))
+func (d postgresDialect) literal(value any) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf(`'%v'`, strings.ReplaceAll(v, "'", "''"))
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'G', -1, 64)
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v))
+	case time.Time:
+		return d.literal(v.Format(time.RFC3339Nano))
+	default:
+		return d.literal(fmt.Sprint(v))
 	}
-	result := descriptor.OpTranslator(expr)
-	result.Expr = fmt.Sprintf("(%v)", result.Expr)
-	return result, nil
+}
+
+func (postgresDialect) placeholder(index int) string {
+	return "$" + strconv.Itoa(index+1)
 }
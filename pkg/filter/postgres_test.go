@@ -1,7 +1,10 @@
 package filter_test
 
 import (
+	"errors"
+	"regexp"
 	"testing"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -14,11 +17,18 @@ func TestTranslator(t *testing.T) {
 	RunSpecs(t, "Translator")
 }
 
+// newTranslator creates the translator of valid identifiers
+func newTranslator(identifiers []filter.Identifier, dialect filter.TranslatorDialect, opts ...filter.TranslatorOption) filter.Translator {
+	trs, err := filter.NewValidatedTranslator(identifiers, dialect, opts...)
+	ExpectWithOffset(1, err).ToNot(HaveOccurred())
+	return trs
+}
+
 var _ = Describe("Postgres translator", func() {
 	var trs filter.Translator
 
 	BeforeEach(func() {
-		trs = filter.NewTranslator([]filter.Identifier{
+		trs = newTranslator([]filter.Identifier{
 			{ExprName: "intField", Type: filter.IdentifierTypeInt},
 			{ExprName: "floatField", Type: filter.IdentifierTypeFloat},
 			{ExprName: "boolField", Type: filter.IdentifierTypeBool},
@@ -134,7 +144,75 @@ var _ = Describe("Postgres translator", func() {
 				_, err := trs.Translate("intField + 2")
 
 				Expect(err).To(HaveOccurred())
-				Expect(err).To(Equal(filter.ErrInvalidFilter))
+				Expect(err).To(MatchError(filter.ErrInvalidFilter))
+			})
+		})
+
+		When("translation error", func() {
+			It("locates the unsupported operation", func() {
+				_, err := trs.Translate("boolField and\n  (intField + 1) == lower(stringField)")
+
+				var translationErr *filter.TranslationError
+				Expect(errors.As(err, &translationErr)).To(BeTrue())
+				Expect(translationErr.Code).To(Equal(filter.ErrorCodeUnsupportedOperation))
+				Expect(translationErr.Start).To(Equal(filter.Position{Line: 2, Column: 3}))
+				Expect(translationErr.End).To(Equal(filter.Position{Line: 2, Column: 39}))
+				Expect(translationErr.Snippet).To(Equal("(intField + 1) == lower(stringField)"))
+				Expect(translationErr.Actual).To(Equal([]string{"expr_int", "string"}))
+				Expect(translationErr.Expected).To(ContainElement([]string{"int", "expr_int"}))
+				Expect(translationErr.Error()).To(Equal(`unsupported_operation: intField + 1 == lower(stringField)`))
+			})
+
+			It("locates the unknown json property", func() {
+				_, err := trs.Translate(`jsonField.nestedProperty1.abcd == "abcd"`)
+
+				var translationErr *filter.TranslationError
+				Expect(errors.As(err, &translationErr)).To(BeTrue())
+				Expect(translationErr.Code).To(Equal(filter.ErrorCodeUnknownIdentifier))
+				Expect(translationErr.Snippet).To(Equal("jsonField.nestedProperty1.abcd"))
+				Expect(translationErr.Start).To(Equal(filter.Position{Line: 1, Column: 1}))
+				Expect(translationErr.End).To(Equal(filter.Position{Line: 1, Column: 31}))
+			})
+
+			It("locates the parsing error", func() {
+				_, err := trs.Translate("intField = nil")
+
+				var translationErr *filter.TranslationError
+				Expect(errors.As(err, &translationErr)).To(BeTrue())
+				Expect(translationErr.Code).To(Equal(filter.ErrorCodeParsingError))
+				Expect(translationErr.Start).To(Equal(filter.Position{Line: 1, Column: 10}))
+				Expect(translationErr.Snippet).To(Equal("="))
+			})
+
+			It("locates the parsing error at the offending token", func() {
+				_, err := trs.Translate("intField ==\n  1 )")
+
+				var translationErr *filter.TranslationError
+				Expect(errors.As(err, &translationErr)).To(BeTrue())
+				Expect(translationErr.Start).To(Equal(filter.Position{Line: 2, Column: 5}))
+				Expect(translationErr.Snippet).To(Equal(")"))
+			})
+
+			It("locates the parsing error at the end of the query", func() {
+				_, err := trs.Translate("intField == 1 and")
+
+				var translationErr *filter.TranslationError
+				Expect(errors.As(err, &translationErr)).To(BeTrue())
+				Expect(translationErr.Code).To(Equal(filter.ErrorCodeParsingError))
+				Expect(translationErr.Start).To(Equal(filter.Position{Line: 1, Column: 18}))
+				Expect(translationErr.End).To(Equal(filter.Position{Line: 1, Column: 18}))
+				Expect(translationErr.Snippet).To(BeEmpty())
+			})
+
+			It("reports the type of non-boolean expression", func() {
+				_, err := trs.Translate("intField + 2")
+
+				var translationErr *filter.TranslationError
+				Expect(errors.As(err, &translationErr)).To(BeTrue())
+				Expect(translationErr.Code).To(Equal(filter.ErrorCodeInvalidFilter))
+				Expect(translationErr.Snippet).To(Equal("intField + 2"))
+				Expect(translationErr.Expected).To(Equal([][]string{{"expr_bool"}, {"bool"}}))
+				Expect(translationErr.Actual).To(Equal([]string{"expr_int"}))
 			})
 		})
 	})
@@ -196,6 +274,56 @@ var _ = Describe("Postgres translator", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(query).To(Equal(filter.SQLWhereCondition("(tsField < '2024-09-17T08:00:01.2345Z')")))
 			})
+
+			It("quotes and qualifies db names", func() {
+				trs = newTranslator([]filter.Identifier{
+					{ExprName: "order", Type: filter.IdentifierTypeString},
+					{ExprName: "customer", DBName: "Customer", Table: "o", Type: filter.IdentifierTypeString},
+					{ExprName: "total", DBName: "sales.orders.total_amount", Type: filter.IdentifierTypeFloat},
+				}, filter.TranslatorDialectPostgres, filter.WithCaseSensitiveNames())
+
+				query, err := trs.Translate(`order == "a" and customer == "b" and total > 1.5`)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(query).To(Equal(filter.SQLWhereCondition(`((("order" = 'a') and (o."Customer" = 'b')) and (sales.orders.total_amount > 1.5))`)))
+			})
+
+			It("leaves mixed-case names to be folded without the option", func() {
+				trs = newTranslator([]filter.Identifier{
+					{ExprName: "order", DBName: "Order", Type: filter.IdentifierTypeString},
+					{ExprName: "customer", DBName: "Customer", Table: "o", Type: filter.IdentifierTypeString},
+				}, filter.TranslatorDialectPostgres)
+
+				query, err := trs.Translate(`order == "a" and customer == "b"`)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(query).To(Equal(filter.SQLWhereCondition(`(("Order" = 'a') and (o.Customer = 'b'))`)))
+			})
+
+			It("rejects db names which are not column names", func() {
+				for _, identifier := range []filter.Identifier{
+					{ExprName: "name", DBName: "lower(name)"},
+					{ExprName: "name", DBName: "name; drop table users"},
+					{ExprName: "name", DBName: `"name"`},
+					{ExprName: "name", DBName: "a..name"},
+					{ExprName: "name", DBName: "db.schema.table.name"},
+					{ExprName: "name", Table: "t --"},
+				} {
+					_, err := filter.NewValidatedTranslator([]filter.Identifier{identifier}, filter.TranslatorDialectPostgres)
+
+					Expect(err).To(MatchError(filter.ErrInvalidIdentifier), identifier.DBName)
+				}
+			})
+
+			It("fails translations referencing identifiers for invalid db names", func() {
+				trs = filter.NewTranslator([]filter.Identifier{
+					{ExprName: "name", DBName: "lower(name)", Type: filter.IdentifierTypeString},
+				}, filter.TranslatorDialectPostgres)
+
+				_, err := trs.Translate(`name == "a"`)
+
+				Expect(err).To(MatchError(filter.ErrInvalidIdentifier))
+			})
 		})
 
 		Describe("json translation", func() {
@@ -256,6 +384,21 @@ var _ = Describe("Postgres translator", func() {
 				Expect(err).ToNot(HaveOccurred())
 				Expect(query).To(Equal(filter.SQLWhereCondition(`(jsonField -> 'nestedProperty1' -> 'nestedProperty2' ->> 'stringProperty' = 'abcd')`)))
 			})
+
+			It("translates timestamps in other formats", func() {
+				trs = newTranslator([]filter.Identifier{
+					{ExprName: "event", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+						"sentAt":  filter.JSONTimestamp(filter.JSONTimestampFormatEpochSeconds),
+						"seenAt":  filter.JSONTimestamp(filter.JSONTimestampFormatEpochMillis),
+						"retries": filter.JSONArray(filter.JSONTimestamp(filter.JSONTimestampFormatEpochMillis)),
+					}},
+				}, filter.TranslatorDialectPostgres)
+
+				query, err := trs.Translate(`event.sentAt < event.seenAt and any(event.retries, # > "2024-09-17T08:00:00Z")`)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(query).To(Equal(filter.SQLWhereCondition("((to_timestamp(cast(event ->> 'sentAt' as float)) < to_timestamp(cast(event ->> 'seenAt' as float) / 1000)) and (EXISTS (SELECT 1 FROM jsonb_array_elements_text(event -> 'retries') AS elem1 WHERE (to_timestamp(cast(elem1 as float) / 1000) > '2024-09-17T08:00:00Z'))))")))
+			})
 		})
 	})
 
@@ -264,7 +407,7 @@ var _ = Describe("Postgres translator", func() {
 			query, err := trs.Translate(`intField >= 3 and jsonField.tsProperty > "2024-09-17T08:00:00Z"`)
 
 			Expect(err).ToNot(HaveOccurred())
-			Expect(query).To(Equal(filter.SQLWhereCondition("((intField >= 3) and (jsonField ->> 'tsProperty' > '2024-09-17T08:00:00Z'))")))
+			Expect(query).To(Equal(filter.SQLWhereCondition("((intField >= 3) and (cast(jsonField ->> 'tsProperty' as timestamptz) > '2024-09-17T08:00:00Z'))")))
 		})
 
 		It("translates unary expressions", func() {
@@ -281,11 +424,963 @@ var _ = Describe("Postgres translator", func() {
 			Expect(query).To(Equal(filter.SQLWhereCondition("(cast(jsonField ->> 'floatProperty' as float) >= ((floatField + 3) - 2))")))
 		})
 
+		It("translates comparison of identifiers", func() {
+			query, err := trs.Translate(`tsField > jsonField.tsProperty and floatField >= jsonField.floatProperty or boolField != jsonField.boolProperty`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((tsField > cast(jsonField ->> 'tsProperty' as timestamptz)) and (floatField >= cast(jsonField ->> 'floatProperty' as float))) or (boolField <> cast(jsonField ->> 'boolProperty' as boolean)))")))
+		})
+
+		It("translates comparison with literal on the left side", func() {
+			query, err := trs.Translate(`42 < intField and "abcd" == stringField`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((42 < intField) and ('abcd' = stringField))")))
+		})
+
+		It("translates comparison of computed expressions", func() {
+			query, err := trs.Translate(`intField * 2 > 10 and 1.5 <= floatField / 2`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((intField * 2) > 10) and (1.5 <= (floatField / 2)))")))
+		})
+
+		It("translates comparison of mixed numeric types", func() {
+			query, err := trs.Translate(`floatField > 1 and intField <= 2.5 and intField / 2 < 1.5`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((floatField > 1) and (intField <= 2.5)) and ((intField / 2) < 1.5))")))
+		})
+
+		It("translates comparison of mixed numeric identifiers", func() {
+			query, err := trs.Translate(`floatField >= intField and intField != jsonField.floatProperty`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`((floatField >= intField) and (intField <> cast(jsonField ->> 'floatProperty' as float)))`)))
+		})
+
+		It("translates comparison with nil on the left side", func() {
+			query, err := trs.Translate(`nil == intField or nil != jsonField.stringProperty`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`((intField IS NULL) or (jsonField ->> 'stringProperty' IS NOT NULL))`)))
+		})
+
+		It("fails for comparison of identifiers of different types", func() {
+			_, err := trs.Translate(`intField == stringField`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+
 		It("translates string expressions", func() {
 			query, err := trs.Translate(`(stringField startsWith "abcd" or stringField endsWith "abcd") and (jsonField.stringProperty matches "[A-Z]+" or jsonField.stringProperty contains "ijkl")`)
 
 			Expect(err).ToNot(HaveOccurred())
-			Expect(query).To(Equal(filter.SQLWhereCondition("(((stringField like 'abcd%%') or (stringField like '%%abcd')) and ((jsonField ->> 'stringProperty' ~ '[A-Z]+') or (jsonField ->> 'stringProperty' like '%%ijkl%%')))")))
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((stringField like 'abcd%') or (stringField like '%abcd')) and ((jsonField ->> 'stringProperty' ~ '[A-Z]+') or (jsonField ->> 'stringProperty' like '%ijkl%')))")))
+		})
+	})
+
+	Describe("string pattern expressions", func() {
+		It("escapes like wildcards", func() {
+			query, err := trs.Translate(`stringField contains "50%" or stringField startsWith "A_" or stringField endsWith "\\"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`(((stringField like '%50\%%') or (stringField like 'A\_%')) or (stringField like '%\\'))`)))
+		})
+
+		It("binds escaped patterns", func() {
+			query, args, err := trs.TranslateParams(`stringField contains "50%_"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(stringField like $1)")))
+			Expect(args).To(Equal([]any{`%50\%\_%`}))
+		})
+	})
+
+	Describe("case-insensitive expressions", func() {
+		It("translates lower and upper functions", func() {
+			query, err := trs.Translate(`lower(stringField) contains "acme" or upper(jsonField.stringProperty) == "ACME"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((lower(stringField) like '%acme%') or (upper(jsonField ->> 'stringProperty') = 'ACME'))")))
+		})
+
+		It("fails for functions on non-string identifiers", func() {
+			_, err := trs.Translate(`lower(intField) == "1"`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+
+		It("translates case-insensitive identifiers", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "name", Type: filter.IdentifierTypeString, CaseInsensitive: true},
+				{ExprName: "code", Type: filter.IdentifierTypeString},
+			}, filter.TranslatorDialectPostgres)
+
+			query, err := trs.Translate(`name contains "acme" and name != "ACME Corp" and code startsWith "A"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((name ilike '%acme%') and (lower(name) <> lower('ACME Corp'))) and (code like 'A%'))")))
+		})
+
+		It("translates all identifiers case-insensitively", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "name", Type: filter.IdentifierTypeString},
+				{ExprName: "attributes", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+					"code": filter.JSONLeaf(filter.IdentifierTypeString),
+				}},
+			}, filter.TranslatorDialectPostgres, filter.WithCaseInsensitiveStrings())
+
+			query, err := trs.Translate(`name endsWith "corp" or attributes.code == "a1" or name == nil`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((name ilike '%corp') or (lower(attributes ->> 'code') = lower('a1'))) or (name IS NULL))")))
+		})
+	})
+
+	Describe("conditional expressions", func() {
+		It("translates ternary operator", func() {
+			query, err := trs.Translate(`(intField > 0 ? floatField : 0) < 100`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(CASE WHEN (intField > 0) THEN floatField ELSE 0 END < 100)")))
+		})
+
+		It("translates nil-coalescing operator", func() {
+			query, args, err := trs.TranslateParams(`(jsonField.intProperty ?? 0) > 5 and (stringField ?? "abcd") contains "bc"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((COALESCE(cast(jsonField ->> 'intProperty' as int), $1) > $2) and (COALESCE(stringField, $3) like $4))")))
+			Expect(args).To(Equal([]any{0, 5, "abcd", "%bc%"}))
+		})
+
+		It("translates nested conditions with nil branches", func() {
+			query, err := trs.Translate(`(boolField ? tsField ?? "2024-09-17T08:00:00Z" : nil) == nil`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(CASE WHEN boolField THEN COALESCE(tsField, '2024-09-17T08:00:00Z') ELSE NULL END IS NULL)")))
+		})
+
+		It("fails for branches of incompatible types", func() {
+			_, err := trs.Translate(`(boolField ? intField : "abcd") == "abcd"`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+
+		It("fails for non-boolean condition", func() {
+			_, err := trs.Translate(`(intField ? 1 : 2) == 1`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+
+		DescribeTable("fails for conditional string patterns",
+			func(query string) {
+				_, err := trs.Translate(query)
+
+				Expect(err).To(HaveOccurred())
+				Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+			},
+			Entry("ternary", `stringField contains (intField > 0 ? "a" : "b")`),
+			Entry("ternary with constant condition", `stringField startsWith (true ? "a" : "b")`),
+			Entry("nil-coalescing", `stringField contains ("a" ?? "b")`),
+			Entry("regular expression", `stringField matches (intField > 0 ? "a" : "b")`),
+		)
+	})
+
+	Describe("functions", func() {
+		It("translates string and numeric functions", func() {
+			query, err := trs.Translate(`len(trim(stringField)) > 3 and ceil(floatField) < 2.5 and abs(intField) == floor(intField)`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`(((length(trim(stringField)) > 3) and (ceil(floatField) < 2.5)) and (abs(intField) = cast(floor(intField) as bigint)))`)))
+		})
+
+		It("translates date functions", func() {
+			query, err := trs.Translate(`tsField > date("2025-01-01") and tsField < now() and date(jsonField.stringProperty) == tsField`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((tsField > '2025-01-01T00:00:00Z') and (tsField < CURRENT_TIMESTAMP)) and (cast(jsonField ->> 'stringProperty' as timestamptz) = tsField))")))
+		})
+
+		It("translates timestamp and duration arithmetic", func() {
+			query, err := trs.Translate(`tsField > now() - duration("24h") and tsField - date(jsonField.stringProperty) < duration("1h30m") and date(tsField) == date("2025-01-01")`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`(((tsField > (CURRENT_TIMESTAMP - make_interval(secs => 86400))) and ((tsField - cast(jsonField ->> 'stringProperty' as timestamptz)) < make_interval(secs => 5400))) and (date_trunc('day', tsField) = '2025-01-01T00:00:00Z'))`)))
+		})
+
+		It("translates now with the clock", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "createdAt", Type: filter.IdentifierTypeTimestamp},
+			}, filter.TranslatorDialectPostgres, filter.WithClock(func() time.Time {
+				return time.Date(2025, 1, 2, 3, 0, 0, 0, time.FixedZone("CET", 3600))
+			}))
+
+			query, args, err := trs.TranslateParams(`createdAt > now() - duration("24h")`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(createdAt > (cast($1 as timestamptz) - make_interval(secs => $2)))")))
+			Expect(args).To(Equal([]any{time.Date(2025, 1, 2, 2, 0, 0, 0, time.UTC), 86400.0}))
+		})
+
+		It("truncates timestamps of the clock to the day", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "createdAt", Type: filter.IdentifierTypeTimestamp},
+			}, filter.TranslatorDialectPostgres, filter.WithClock(func() time.Time {
+				return time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC)
+			}))
+
+			query, args, err := trs.TranslateParams(`date(createdAt) == date(now()) and createdAt < date("2025-01-02T03:00:00Z")`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`((date_trunc('day', createdAt) = $1) and (createdAt < $2))`)))
+			Expect(args).To(Equal([]any{time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC), time.Date(2025, 1, 2, 3, 0, 0, 0, time.UTC)}))
+		})
+
+		It("fails for invalid timestamp arithmetic", func() {
+			for _, query := range []string{
+				`tsField + tsField > tsField`,
+				`duration("1h") - tsField > tsField`,
+				`tsField - duration(stringField) > tsField`,
+				`tsField - tsField > 1`,
+			} {
+				_, err := trs.Translate(query)
+
+				Expect(filter.IsUnsupportedOperation(err)).To(BeTrue(), query)
+			}
+		})
+
+		It("translates date part accessors", func() {
+			query, err := trs.Translate(`tsField.Year() == 2025 and tsField.Weekday() in [0, 6] and date(jsonField.stringProperty).Second() < 30 and now().Hour() >= 9`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((((cast(extract(year from tsField) as integer) = 2025) and (cast(extract(dow from tsField) as integer) IN (0, 6))) and (cast(floor(extract(second from cast(jsonField ->> 'stringProperty' as timestamptz))) as integer) < 30)) and (cast(extract(hour from CURRENT_TIMESTAMP) as integer) >= 9))")))
+		})
+
+		It("fails for date part accessors of other types", func() {
+			for _, query := range []string{
+				`intField.Year() == 2025`,
+				`tsField.Unix() > 0`,
+				`tsField.Year(1) == 2025`,
+			} {
+				_, err := trs.Translate(query)
+
+				Expect(filter.IsUnsupportedOperation(err)).To(BeTrue(), query)
+			}
+		})
+
+		It("translates custom functions", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "lat", Type: filter.IdentifierTypeFloat},
+				{ExprName: "lng", Type: filter.IdentifierTypeFloat},
+			}, filter.TranslatorDialectPostgres, filter.WithFunction("distance", filter.Function{
+				SQL:    "ST_Distance(ST_MakePoint({1}, {0}), ST_MakePoint({3}, {2}))",
+				Args:   []filter.IdentifierType{filter.IdentifierTypeFloat, filter.IdentifierTypeFloat, filter.IdentifierTypeFloat, filter.IdentifierTypeFloat},
+				Result: filter.IdentifierTypeFloat,
+			}))
+
+			query, args, err := trs.TranslateParams(`distance(lat, lng, 40.7, -74) < 1000.0`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(ST_Distance(ST_MakePoint(lng, lat), ST_MakePoint(($1), $2)) < $3)")))
+			Expect(args).To(Equal([]any{-74, 40.7, 1000.0}))
+		})
+
+		It("fails for invalid date literals", func() {
+			_, err := trs.Translate(`tsField > date("yesterday")`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+
+		It("fails for wrong number of arguments", func() {
+			_, err := trs.Translate(`abs(intField, 1) == 1`)
+
+			var translationErr *filter.TranslationError
+			Expect(errors.As(err, &translationErr)).To(BeTrue())
+			Expect(translationErr.Expected).To(Equal([][]string{{"int", "expr_int", "float", "expr_float"}}))
+			Expect(translationErr.Actual).To(Equal([]string{"int", "expr_int"}))
+		})
+
+		It("fails for unknown functions", func() {
+			_, err := trs.Translate(`distance(floatField, 1) < 1`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+	})
+
+	Describe("membership expressions", func() {
+		It("translates in", func() {
+			query, err := trs.Translate(`intField in [1, 2, 3] and stringField in ["a", "b"]`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((intField IN (1, 2, 3)) and (stringField IN ('a', 'b')))")))
+		})
+
+		It("translates not in", func() {
+			query, err := trs.Translate(`jsonField.intProperty not in [1, -2] or tsField not in ["2024-09-17T08:00:00Z"]`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((cast(jsonField ->> 'intProperty' as int) NOT IN (1, (-2))) or (tsField NOT IN ('2024-09-17T08:00:00Z')))")))
+		})
+
+		It("unifies numeric elements", func() {
+			query, err := trs.Translate(`floatField in [1, 2.5]`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(floatField IN (1, 2.5))")))
+		})
+
+		It("translates mixed numeric membership", func() {
+			query, err := trs.Translate(`floatField in [1, 2] and intField not in [1, 2.5]`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`((floatField IN (1, 2)) and (intField NOT IN (1, 2.5)))`)))
+		})
+
+		It("translates empty arrays to constants", func() {
+			query, err := trs.Translate(`intField in [] or stringField not in []`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((FALSE) or (TRUE))")))
+		})
+
+		It("binds elements", func() {
+			query, args, err := trs.TranslateParams(`intField in [1, 2] and stringField not in ["a"]`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((intField IN ($1, $2)) and (stringField NOT IN ($3)))")))
+			Expect(args).To(Equal([]any{1, 2, "a"}))
+		})
+
+		It("fails for elements incompatible with identifier", func() {
+			_, err := trs.Translate(`intField in ["a", "b"]`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+
+		It("fails for mixed elements", func() {
+			_, err := trs.Translate(`stringField in ["a", 1]`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+
+		It("fails for nil elements", func() {
+			_, err := trs.Translate(`intField in [1, nil]`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+	})
+
+	Describe("array expressions", func() {
+		BeforeEach(func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "tags", Type: filter.IdentifierTypeArray(filter.IdentifierTypeString)},
+				{ExprName: "scores", Type: filter.IdentifierTypeArray(filter.IdentifierTypeInt)},
+				{ExprName: "intField", Type: filter.IdentifierTypeInt},
+			}, filter.TranslatorDialectPostgres)
+		})
+
+		It("translates membership", func() {
+			query, err := trs.Translate(`"vip" in tags and intField not in scores`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(('vip' = ANY(tags)) and (NOT (intField = ANY(scores))))")))
+		})
+
+		It("translates predicates", func() {
+			query, err := trs.Translate(`any(tags, # startsWith "eu-") and all(scores, # > 50) or none(scores, # < 0) and one(tags, # == "vip")`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((EXISTS (SELECT 1 FROM unnest(tags) AS elem1 WHERE (elem1 like 'eu-%'))) and (NOT EXISTS (SELECT 1 FROM unnest(scores) AS elem1 WHERE (elem1 > 50) IS NOT TRUE))) or ((NOT EXISTS (SELECT 1 FROM unnest(scores) AS elem1 WHERE (elem1 < 0))) and ((SELECT count(*) FROM unnest(tags) AS elem1 WHERE (elem1 = 'vip')) = 1)))")))
+		})
+
+		It("translates length of arrays", func() {
+			query, err := trs.Translate(`len(tags) > 2 and len(filter(scores, # > intField)) == 0`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((cardinality(tags) > 2) and (cardinality((SELECT coalesce(array_agg(elem1), '{}') FROM unnest(scores) AS elem1 WHERE (elem1 > intField))) = 0))")))
+		})
+
+		It("translates nested predicates", func() {
+			query, err := trs.Translate(`any(tags, # == "vip" and any(scores, # > 3))`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(EXISTS (SELECT 1 FROM unnest(tags) AS elem1 WHERE ((elem1 = 'vip') and (EXISTS (SELECT 1 FROM unnest(scores) AS elem2 WHERE (elem2 > 3))))))")))
+		})
+
+		It("binds predicate literals", func() {
+			query, args, err := trs.TranslateParams(`any(scores, # > 3) and "vip" in tags`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((EXISTS (SELECT 1 FROM unnest(scores) AS elem1 WHERE (elem1 > $1))) and ($2 = ANY(tags)))")))
+			Expect(args).To(Equal([]any{3, "vip"}))
+		})
+
+		It("fails for predicates of non-arrays", func() {
+			_, err := trs.Translate(`any(intField, # > 3)`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+
+		It("fails for non-boolean predicates", func() {
+			_, err := trs.Translate(`all(scores, # + 1)`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+
+		It("fails for elements outside of predicates", func() {
+			_, err := trs.Translate(`# > 3`)
+
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails for membership of incompatible elements", func() {
+			_, err := trs.Translate(`1 in tags`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+	})
+
+	Describe("json array expressions", func() {
+		BeforeEach(func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "order", DBName: "order_data", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+					"items": filter.JSONArray(filter.JSONTree{
+						"sku": filter.JSONLeaf(filter.IdentifierTypeString),
+						"qty": filter.JSONLeaf(filter.IdentifierTypeInt),
+					}),
+					"tags":   filter.JSONArray(filter.JSONLeaf(filter.IdentifierTypeString)),
+					"scores": filter.JSONArray(filter.JSONLeaf(filter.IdentifierTypeInt)),
+				}},
+				{ExprName: "intField", Type: filter.IdentifierTypeInt},
+			}, filter.TranslatorDialectPostgres)
+		})
+
+		It("translates element access", func() {
+			query, err := trs.Translate(`order.items[0].sku == "X" and order.scores[1] > 3`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((order_data -> 'items' -> 0 ->> 'sku' = 'X') and (cast(order_data -> 'scores' ->> 1 as int) > 3))")))
+		})
+
+		It("translates predicates on elements", func() {
+			query, err := trs.Translate(`any(order.items, .qty > 3) and all(order.scores, # >= intField)`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`((EXISTS (SELECT 1 FROM jsonb_array_elements(order_data -> 'items') AS elem1 WHERE (cast(elem1 ->> 'qty' as int) > 3))) and (NOT EXISTS (SELECT 1 FROM jsonb_array_elements_text(order_data -> 'scores') AS elem1 WHERE (cast(elem1 as int) >= intField) IS NOT TRUE)))`)))
+		})
+
+		It("translates membership", func() {
+			query, args, err := trs.TranslateParams(`"gift" in order.tags and intField not in order.scores`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((order_data -> 'tags' @> jsonb_build_array(cast($1 as text))) and (NOT (order_data -> 'scores' @> jsonb_build_array(cast(intField as bigint)))))")))
+			Expect(args).To(Equal([]any{"gift"}))
+		})
+
+		It("translates length and filter", func() {
+			query, err := trs.Translate(`len(order.tags) > 1 and len(filter(order.items, .sku startsWith "A")) == 2`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((jsonb_array_length(order_data -> 'tags') > 1) and (jsonb_array_length((SELECT coalesce(jsonb_agg(elem1), '[]') FROM jsonb_array_elements(order_data -> 'items') AS elem1 WHERE (elem1 ->> 'sku' like 'A%'))) = 2))")))
+		})
+
+		It("fails for index access of objects", func() {
+			_, err := trs.Translate(`order[0] == nil`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+
+		It("fails for keys of arrays", func() {
+			_, err := trs.Translate(`order.items.sku == "X"`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+
+		It("fails for unknown element properties", func() {
+			_, err := trs.Translate(`any(order.items, .price > 3)`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnknownIdentifier(err)).To(BeTrue())
+		})
+
+		It("fails for membership of incompatible values", func() {
+			_, err := trs.Translate(`1 in order.tags`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+	})
+
+	Describe("dynamic json expressions", func() {
+		BeforeEach(func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "attrs", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONDynamic(nil)},
+				{ExprName: "meta", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+					"labels": filter.JSONDynamic(regexp.MustCompile(`^[a-z_]+$`)),
+				}},
+				{ExprName: "intField", Type: filter.IdentifierTypeInt},
+			}, filter.TranslatorDialectPostgres)
+		})
+
+		It("infers types from the other operand", func() {
+			query, err := trs.Translate(`attrs.size > 3 and attrs.color == "red" and attrs.ratio < 0.5 and attrs.active == true`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((((cast(attrs ->> 'size' as float) > 3) and (attrs ->> 'color' = 'red')) and (cast(attrs ->> 'ratio' as float) < 0.5)) and (cast(attrs ->> 'active' as boolean) = TRUE))")))
+		})
+
+		It("infers types of membership, nil comparison and coalescing", func() {
+			query, err := trs.Translate(`attrs.color in ["red", "blue"] and (attrs.size ?? 0) > 1 and meta.labels.env != nil`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((attrs ->> 'color' IN ('red', 'blue')) and (COALESCE(cast(attrs ->> 'size' as float), 0) > 1)) and (meta -> 'labels' ->> 'env' IS NOT NULL))")))
+		})
+
+		It("translates cast functions", func() {
+			query, err := trs.Translate(`int(attrs.dims.width) * 2 > intField and bool(attrs.active) and float(attrs.size + attrs.extra) > 1.5`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((((cast(attrs -> 'dims' ->> 'width' as int) * 2) > intField) and cast(attrs ->> 'active' as boolean)) and ((cast(attrs ->> 'size' as float) + cast(attrs ->> 'extra' as float)) > 1.5))")))
+		})
+
+		It("quotes keys", func() {
+			query, args, err := trs.TranslateParams(`attrs["it's ?"] == "x"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(attrs ->> 'it''s ?' = $1)")))
+			Expect(args).To(Equal([]any{"x"}))
+		})
+
+		It("fails for values of unknown type", func() {
+			_, err := trs.Translate(`attrs.size == attrs.weight`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+
+		It("fails for keys not matching the pattern", func() {
+			_, err := trs.Translate(`meta.labels["Env"] == "prod"`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnknownIdentifier(err)).To(BeTrue())
+		})
+
+		It("fails for keys which cannot be quoted", func() {
+			_, err := trs.Translate(`attrs["a\"b"] == "x"`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+
+		It("fails for casts of other values", func() {
+			_, err := trs.Translate(`int(3.5) > 1`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+	})
+
+	Describe("json containment", func() {
+		BeforeEach(func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "event", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+					"user": filter.JSONTree{
+						"name": filter.JSONLeaf(filter.IdentifierTypeString),
+						"age":  filter.JSONLeaf(filter.IdentifierTypeInt),
+					},
+					"at":    filter.JSONLeaf(filter.IdentifierTypeTimestamp),
+					"items": filter.JSONArray(filter.JSONTree{"qty": filter.JSONLeaf(filter.IdentifierTypeInt)}),
+					"attrs": filter.JSONDynamic(nil),
+				}},
+				{ExprName: "intField", Type: filter.IdentifierTypeInt},
+			}, filter.TranslatorDialectPostgres, filter.WithJSONContainment())
+		})
+
+		It("translates equality to containment", func() {
+			query, err := trs.Translate(`event.user.name == "it's" and true == event.attrs.vip and event.attrs.score == 1.5`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`(((event @> '{"user":{"name":"it''s"}}') and (event @> '{"attrs":{"vip":true}}')) and (event @> '{"attrs":{"score":1.5}}'))`)))
+		})
+
+		It("translates other comparisons to json path filters", func() {
+			query, err := trs.Translate(`event.user.age >= 18 and 65 > event.user.age or event.items[0].qty == 2`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`(((event @? '$.user.age ? (@ >= 18)') and (event @? '$.user.age ? (@ < 65)')) or (event @? '$.items[0].qty ? (@ == 2)'))`)))
+		})
+
+		It("quotes keys which are not plain names in json path filters", func() {
+			query, err := trs.Translate(`event.attrs["$id"] >= 3 and event.attrs["a$b"] < 2`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`((event @? '$.attrs."$id" ? (@ >= 3)') and (event @? '$.attrs."a$b" ? (@ < 2)'))`)))
+		})
+
+		It("keeps comparisons which would match missing or null properties", func() {
+			query, err := trs.Translate(`not (event.user.age == 3) and event.user.name != "x" and (event.user.age > 3) != true`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`(((not (cast(event -> 'user' ->> 'age' as int) = 3)) and (event -> 'user' ->> 'name' <> 'x')) and ((cast(event -> 'user' ->> 'age' as int) > 3) <> TRUE))`)))
+		})
+
+		It("translates comparisons of json array elements", func() {
+			query, err := trs.Translate(`any(event.items, .qty == 3)`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`(EXISTS (SELECT 1 FROM jsonb_array_elements(event -> 'items') AS elem1 WHERE (elem1 @> '{"qty":3}')))`)))
+		})
+
+		It("keeps other comparisons", func() {
+			query, err := trs.Translate(`event.user.age > intField and event.user.name == nil and event.at > "2024-09-17T08:00:00Z"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((cast(event -> 'user' ->> 'age' as int) > intField) and (event -> 'user' ->> 'name' IS NULL)) and (cast(event ->> 'at' as timestamptz) > '2024-09-17T08:00:00Z'))")))
+		})
+
+		It("binds json documents", func() {
+			query, args, err := trs.TranslateParams(`event.user.name == "x" and event.user.age < 3`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((event @> $1) and (event @? $2))")))
+			Expect(args).To(Equal([]any{`{"user":{"name":"x"}}`, `$.user.age ? (@ < 3)`}))
+		})
+
+		It("fails for mismatched types", func() {
+			_, err := trs.Translate(`event.user.age == "x"`)
+
+			Expect(err).To(HaveOccurred())
+			Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+		})
+	})
+
+	Describe("simplified translation", func() {
+		BeforeEach(func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "intField", Type: filter.IdentifierTypeInt},
+				{ExprName: "floatField", Type: filter.IdentifierTypeFloat},
+				{ExprName: "boolField", Type: filter.IdentifierTypeBool},
+				{ExprName: "stringField", Type: filter.IdentifierTypeString},
+				{ExprName: "arrayField", Type: filter.IdentifierTypeArray(filter.IdentifierTypeInt)},
+			}, filter.TranslatorDialectPostgres, filter.WithSimplifiedSQL())
+		})
+
+		It("folds constants", func() {
+			query, args, err := trs.TranslateParams(`1 + 2 > intField and floatField <= 2.5 * -2 and intField in [2 * 3, 10 % 4] and (true and boolField) and not not boolField`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`$1 > intField and floatField <= $2 and intField IN ($3, $4) and boolField and boolField`)))
+			Expect(args).To(Equal([]any{3, -5.0, 6, 2}))
+		})
+
+		It("removes terms decided by constants", func() {
+			for query, expected := range map[string]filter.SQLWhereCondition{
+				`false or intField > 1 or 2 > 1`: `TRUE`,
+				`1 > 2 and boolField`:            `FALSE`,
+				`boolField or 1 == 2`:            `boolField`,
+				`not (1 > 2) and boolField`:      `boolField`,
+			} {
+				query, err := trs.Translate(query)
+
+				Expect(err).ToNot(HaveOccurred())
+				Expect(query).To(Equal(expected))
+			}
+		})
+
+		It("keeps operations which differ between dialects", func() {
+			query, err := trs.Translate(`intField > 7 / 2 and intField < 2 ** 3 and floatField > 1 / 0.0`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`intField > 7 / 2 and intField < (2 ^ 3) and floatField > 1 / 0`)))
+		})
+
+		It("compares large integers exactly", func() {
+			query, err := trs.Translate(`9007199254740993 == 9007199254740992 or boolField`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`boolField`)))
+		})
+
+		It("keeps float arithmetic overflowing to infinity", func() {
+			query, args, err := trs.TranslateParams(`1e308 * 10.0 > floatField`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`$1 * $2 > floatField`)))
+			Expect(args).To(Equal([]any{1e308, 10.0}))
+		})
+
+		It("emits parentheses required by precedence", func() {
+			query, err := trs.Translate(`(intField > 1 or boolField) and not (stringField == "a" and boolField) and intField - (floatField - 1) * 2 > -(intField + 1)`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`(intField > 1 or boolField) and not (stringField = 'a' and boolField) and intField - (floatField - 1) * 2 > -(intField + 1)`)))
+		})
+
+		It("parenthesizes conditions of predicates", func() {
+			query, err := trs.Translate(`all(arrayField, # > 1 or # < -1) and any(arrayField, # > 1 and # < 3)`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`(NOT EXISTS (SELECT 1 FROM unnest(arrayField) AS elem1 WHERE (elem1 > 1 or elem1 < -1) IS NOT TRUE)) and (EXISTS (SELECT 1 FROM unnest(arrayField) AS elem1 WHERE elem1 > 1 and elem1 < 3))`)))
+		})
+
+		It("fails like queries as written", func() {
+			plain := newTranslator([]filter.Identifier{
+				{ExprName: "intField", Type: filter.IdentifierTypeInt},
+				{ExprName: "stringField", Type: filter.IdentifierTypeString},
+			}, filter.TranslatorDialectPostgres)
+			for _, query := range []string{`1 > 2 and intField`, `true or stringField > 1`, `1 + 2`} {
+				_, err := trs.Translate(query)
+				_, expected := plain.Translate(query)
+
+				Expect(err).To(HaveOccurred(), query)
+				Expect(err).To(Equal(expected), query)
+			}
+		})
+	})
+
+	Describe("order by translation", func() {
+		It("translates sort keys", func() {
+			orderBy, err := trs.TranslateOrderBy("intField desc, tsField ASC, jsonField.nestedProperty1.nestedProperty2.stringProperty desc nulls last, stringField nulls first")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(orderBy).To(Equal(filter.SQLOrderBy(`intField DESC, tsField ASC, jsonField -> 'nestedProperty1' -> 'nestedProperty2' ->> 'stringProperty' DESC NULLS LAST, stringField ASC NULLS FIRST`)))
+		})
+
+		It("parses sort keys with commas in json keys", func() {
+			keys, err := filter.ParseSort(`jsonField["a, b"] desc,intField`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(keys).To(Equal([]filter.SortKey{{Expr: `jsonField["a, b"]`, Desc: true}, {Expr: "intField"}}))
+		})
+
+		It("fails for keys which are not sortable", func() {
+			for _, sort := range []string{"jsonField", "intField + 1", "lower(stringField)", "intField,", "intField sideways"} {
+				_, err := trs.TranslateOrderBy(sort)
+
+				Expect(err).To(HaveOccurred(), sort)
+				Expect(filter.IsUnsupportedOperation(err) || filter.IsParsingError(err)).To(BeTrue(), sort)
+			}
+		})
+
+		It("locates errors within the sort keys", func() {
+			_, err := trs.TranslateOrderBy("intField desc, unknownField asc")
+
+			var translationErr *filter.TranslationError
+			Expect(errors.As(err, &translationErr)).To(BeTrue())
+			Expect(translationErr.Code).To(Equal(filter.ErrorCodeUnknownIdentifier))
+			Expect(translationErr.Start).To(Equal(filter.Position{Line: 1, Column: 16}))
+			Expect(translationErr.Snippet).To(Equal("unknownField"))
+		})
+	})
+
+	Describe("relation translation", func() {
+		var company *filter.Relation
+
+		BeforeEach(func() {
+			company = &filter.Relation{Table: "companies", Column: "id", Identifiers: []filter.Identifier{
+				{ExprName: "name", Type: filter.IdentifierTypeString},
+			}}
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "id", Table: "orders", Type: filter.IdentifierTypeInt},
+				{ExprName: "intField", Type: filter.IdentifierTypeInt},
+				{ExprName: "customer", DBName: "customer_id", Table: "orders", Type: filter.IdentifierTypeRelation, Relation: &filter.Relation{
+					Table: "crm.customers", Column: "id", Identifiers: []filter.Identifier{
+						{ExprName: "country", Type: filter.IdentifierTypeString},
+						{ExprName: "attrs", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{"tier": filter.JSONLeaf(filter.IdentifierTypeInt)}},
+						{ExprName: "company", DBName: "company_id", Type: filter.IdentifierTypeRelation, Relation: company},
+					},
+				}},
+				{ExprName: "lineItems", DBName: "orders.id", Type: filter.IdentifierTypeRelation, Relation: &filter.Relation{
+					Table: "line_items", Column: "order_id", Many: true, Identifiers: []filter.Identifier{
+						{ExprName: "sku", Type: filter.IdentifierTypeString},
+						{ExprName: "quantity", DBName: "qty", Type: filter.IdentifierTypeInt},
+						{ExprName: "tags", Type: filter.IdentifierTypeArray(filter.IdentifierTypeString)},
+						{ExprName: "product", DBName: "product_id", Type: filter.IdentifierTypeRelation, Relation: company},
+					},
+				}},
+			}, filter.TranslatorDialectPostgres, filter.WithJSONContainment())
+		})
+
+		It("translates columns of a single related row to correlated subqueries", func() {
+			query, err := trs.Translate(`customer.country == "DE" and customer.attrs.tier > 2 and customer.company.name startsWith "A"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`((((SELECT rel1.country FROM crm.customers AS rel1 WHERE rel1.id = orders.customer_id) = 'DE') and ` +
+				`((SELECT cast(rel1.attrs ->> 'tier' as int) FROM crm.customers AS rel1 WHERE rel1.id = orders.customer_id) > 2)) and ` +
+				`((SELECT (SELECT rel2.name FROM companies AS rel2 WHERE rel2.id = rel1.company_id) FROM crm.customers AS rel1 WHERE rel1.id = orders.customer_id) like 'A%'))`)))
+		})
+
+		It("translates predicates over many related rows to EXISTS subqueries", func() {
+			query, err := trs.Translate(`any(lineItems, .sku startsWith "X" and .product.name == "P") or all(lineItems, .quantity > 1 and "a" in .tags)`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`((EXISTS (SELECT 1 FROM line_items AS rel1 WHERE rel1.order_id = orders.id AND ` +
+				`((rel1.sku like 'X%') and ((SELECT rel2.name FROM companies AS rel2 WHERE rel2.id = rel1.product_id) = 'P')))) or ` +
+				`(NOT EXISTS (SELECT 1 FROM line_items AS rel1 WHERE rel1.order_id = orders.id AND ((rel1.qty > 1) and ('a' = ANY(rel1.tags))) IS NOT TRUE)))`)))
+		})
+
+		It("translates counts of related rows referencing the row", func() {
+			query, err := trs.Translate(`one(lineItems, .quantity == id)`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`((SELECT count(*) FROM line_items AS rel1 WHERE rel1.order_id = orders.id AND (rel1.qty = orders.id)) = 1)`)))
+		})
+
+		It("fails for many related rows outside of predicates, and unknown columns", func() {
+			for _, query := range []string{`lineItems.sku == "X"`, `len(filter(lineItems, .quantity > 1)) > 0`, `customer == nil`, `any(customer, .country == "DE")`} {
+				_, err := trs.Translate(query)
+
+				Expect(filter.IsUnsupportedOperation(err)).To(BeTrue(), query)
+			}
+			_, err := trs.Translate(`customer.city == "Berlin"`)
+
+			Expect(filter.IsUnknownIdentifier(err)).To(BeTrue())
+		})
+
+		It("fails for unqualified columns of the row within predicates over related rows", func() {
+			query, err := trs.Translate(`intField > 1 and any(lineItems, .quantity > id)`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`((intField > 1) and (EXISTS (SELECT 1 FROM line_items AS rel1 WHERE rel1.order_id = orders.id AND (rel1.qty > orders.id))))`)))
+
+			_, err = trs.Translate(`any(lineItems, .quantity > intField)`)
+
+			var translationErr *filter.TranslationError
+			Expect(errors.As(err, &translationErr)).To(BeTrue())
+			Expect(translationErr.Code).To(Equal(filter.ErrorCodeUnsupportedOperation))
+			Expect(translationErr.Snippet).To(Equal("intField"))
+		})
+
+		It("fails for relations joined by unqualified columns", func() {
+			for _, relation := range []filter.Identifier{
+				{ExprName: "company", DBName: "company_id", Type: filter.IdentifierTypeRelation, Relation: company},
+				{ExprName: "company", DBName: "orders.company_id", Type: filter.IdentifierTypeRelation},
+				{ExprName: "company", DBName: "orders.company_id", Type: filter.IdentifierTypeRelation, Relation: &filter.Relation{Table: "companies", Column: "id; --"}},
+				{ExprName: "company", DBName: "orders.company_id", Type: filter.IdentifierTypeRelation, Relation: &filter.Relation{Table: "companies", Column: "id", Identifiers: []filter.Identifier{
+					{ExprName: "name", DBName: "companies.name", Type: filter.IdentifierTypeString},
+				}}},
+			} {
+				_, err := filter.NewValidatedTranslator([]filter.Identifier{relation}, filter.TranslatorDialectPostgres)
+
+				Expect(err).To(MatchError(filter.ErrInvalidIdentifier))
+			}
+		})
+	})
+
+	Describe("having translation", func() {
+		It("translates aggregates", func() {
+			query, err := trs.TranslateHaving(`count() > 10 and avg(floatField) < 50 and sum(intField) >= 2 and max(tsField) > "2024-09-17T08:00:00Z" and min(jsonField.stringProperty) == "a"`, "")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLHavingCondition(`(((((count(*) > 10) and (avg(floatField) < 50)) and (sum(intField) >= 2)) and (max(tsField) > '2024-09-17T08:00:00Z')) and (min(jsonField ->> 'stringProperty') = 'a'))`)))
+		})
+
+		It("translates grouping keys outside of aggregates", func() {
+			query, args, err := trs.TranslateHavingParams(`stringField in ["a", "b"] and jsonField["intProperty"] > 1 or count(boolField) > 2`, "stringField, jsonField.intProperty")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLHavingCondition(`(((stringField IN ($1, $2)) and (cast(jsonField ->> 'intProperty' as int) > $3)) or (count(boolField) > $4))`)))
+			Expect(args).To(Equal([]any{"a", "b", 1, 2}))
+		})
+
+		It("translates group by", func() {
+			groupBy, err := trs.TranslateGroupBy("stringField, jsonField.nestedProperty1.nestedProperty2.stringProperty")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(groupBy).To(Equal(filter.SQLGroupBy(`stringField, jsonField -> 'nestedProperty1' -> 'nestedProperty2' ->> 'stringProperty'`)))
+		})
+
+		It("fails for identifiers which are neither grouping keys nor aggregated", func() {
+			_, err := trs.TranslateHaving(`intField > 1 and count() > 1`, "stringField")
+
+			var translationErr *filter.TranslationError
+			Expect(errors.As(err, &translationErr)).To(BeTrue())
+			Expect(translationErr.Code).To(Equal(filter.ErrorCodeUnsupportedOperation))
+			Expect(translationErr.Snippet).To(Equal("intField"))
+		})
+
+		It("fails for aggregates of other types, nested aggregates and aggregates in where conditions", func() {
+			for _, query := range []string{`sum(stringField) > 1`, `min(boolField)`, `count(sum(intField)) > 1`, `any([1], count() > #)`} {
+				_, err := trs.TranslateHaving(query, "")
+
+				Expect(filter.IsUnsupportedOperation(err)).To(BeTrue(), query)
+			}
+			_, err := trs.Translate(`count() > 1`)
+
+			Expect(filter.IsParsingError(err)).To(BeTrue())
+		})
+
+		It("fails for invalid grouping keys", func() {
+			for _, groupBy := range []string{"jsonField", "unknownField", "stringField,", "stringField desc"} {
+				_, err := trs.TranslateHaving(`count() > 1`, groupBy)
+
+				Expect(err).To(HaveOccurred(), groupBy)
+			}
+		})
+	})
+
+	Describe("parameterized translation", func() {
+		It("binds literals as typed arguments", func() {
+			query, args, err := trs.TranslateParams(`intField == 2 and floatField > 1.5 and stringField != "ab'cd" and boolField == true`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((((intField = $1) and (floatField > $2)) and (stringField <> $3)) and (boolField = $4))")))
+			Expect(args).To(Equal([]any{2, 1.5, "ab'cd", true}))
+		})
+
+		It("binds timestamps in UTC", func() {
+			query, args, err := trs.TranslateParams(`tsField < "2024-09-17T08:00:00+03:00"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(tsField < $1)")))
+			Expect(args).To(Equal([]any{time.Date(2024, 9, 17, 5, 0, 0, 0, time.UTC)}))
+		})
+
+		It("keeps nil inline", func() {
+			query, args, err := trs.TranslateParams("intField == nil or jsonField.intProperty != nil")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((intField IS NULL) or (cast(jsonField ->> 'intProperty' as int) IS NOT NULL))")))
+			Expect(args).To(BeEmpty())
+		})
+
+		It("binds negative numbers", func() {
+			query, args, err := trs.TranslateParams("jsonField.intProperty == -2")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(cast(jsonField ->> 'intProperty' as int) = ($1))")))
+			Expect(args).To(Equal([]any{-2}))
+		})
+
+		It("binds string patterns", func() {
+			query, args, err := trs.TranslateParams(`stringField startsWith "abcd" and jsonField.stringProperty matches "[A-Z]+"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((stringField like $1) and (jsonField ->> 'stringProperty' ~ $2))")))
+			Expect(args).To(Equal([]any{"abcd%", "[A-Z]+"}))
+		})
+
+		It("numbers placeholders in order of appearance", func() {
+			query, args, err := trs.TranslateParams(`jsonField.floatProperty >= floatField + 3.5 - 2.5 or intField == 7`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((cast(jsonField ->> 'floatProperty' as float) >= ((floatField + $1) - $2)) or (intField = $3))")))
+			Expect(args).To(Equal([]any{3.5, 2.5, 7}))
 		})
 	})
 })
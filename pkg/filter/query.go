@@ -0,0 +1,254 @@
+package filter
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/expr-lang/expr/ast"
+
+	"github.com/happening-oss/expr2sql/pkg/filter/internal"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Query selects the rows of the table matching the filter, ordered by the sort keys and paged by their values
+type Query struct {
+	With   string    // common table expressions preceding the SELECT, e.g. recent AS (SELECT ...), inserted verbatim
+	Table  string    // table, view or common table expression, optionally qualified by the schema
+	Filter string    // condition on the rows, all rows if empty
+	Sort   []SortKey // the last key needs to be unique, e.g. the primary key, for rows not to be skipped between pages
+	Limit  int       // number of rows of the page, all rows if 0
+	After  string    // cursor of the last row of the previous page, the first page if empty
+}
+
+type SQLQuery string
+
+// QueryBuilder builds complete queries with keyset pagination: instead of an OFFSET, the page starts after the row
+// of the cursor, which encodes its values of the sort keys. Sort keys are expected not to be NULL.
+type QueryBuilder interface {
+	// Build builds the SELECT with bind placeholders for all literals and cursor values,
+	// returning their values in placeholder order
+	Build(query Query) (SQLQuery, []any, error)
+}
+
+// NewQueryBuilder creates a builder of queries referencing the allowed identifiers, like NewValidatedTranslator
+func NewQueryBuilder(allowedIdentifiers []Identifier, dialect TranslatorDialect, opts ...TranslatorOption) (QueryBuilder, error) {
+	t, err := NewValidatedTranslator(allowedIdentifiers, dialect, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &queryBuilder{translator: t.(*translator)}, nil
+}
+
+// EncodeCursor encodes the values of the sort keys of the last row of a page, in the order of the sort keys,
+// as the opaque cursor of the next page, which is only valid for the same sort keys
+func EncodeCursor(sort []SortKey, values ...any) (string, error) {
+	data, err := json.Marshal(cursor{Sort: sortHash(sort), Values: values})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// cursor is the JSON document within the encoded cursor
+type cursor struct {
+	Sort   string `json:"sort"` // hash of the sort keys, as the values would seek other positions for other sort keys
+	Values []any  `json:"values"`
+}
+
+// sortHash hashes the expressions and the directions of the sort keys
+func sortHash(sort []SortKey) string {
+	h := fnv.New64a()
+	for _, key := range sort {
+		fmt.Fprintf(h, "%s\x00%t\x00%d\x00", strings.TrimSpace(key.Expr), key.Desc, key.Nulls)
+	}
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+type queryBuilder struct {
+	translator *translator
+}
+
+func (b *queryBuilder) Build(query Query) (SQLQuery, []any, error) {
+	table, err := b.table(query.Table)
+	if err != nil {
+		return "", nil, err
+	}
+	keys := make([]internal.TranslationResult, 0, len(query.Sort))
+	for _, sortKey := range query.Sort {
+		key, err := b.translator.translateKey(sortKey.Expr, 0, sortKey.Expr, "sorting by")
+		if err != nil {
+			return "", nil, err
+		}
+		keys = append(keys, key)
+	}
+	var conditions []internal.TranslationResult
+	if query.Filter != "" {
+		condition, err := b.translator.translateCondition(query.Filter)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	if query.After != "" {
+		condition, err := b.seek(query.Sort, keys, query.After)
+		if err != nil {
+			return "", nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+
+	var sb strings.Builder
+	var args []any
+	if query.With != "" {
+		sb.WriteString("WITH " + internal.EscapePlaceholders(query.With) + " ")
+	}
+	sb.WriteString("SELECT * FROM " + table)
+	for i, condition := range conditions {
+		if i == 0 {
+			sb.WriteString(" WHERE ")
+		} else {
+			sb.WriteString(" AND ")
+		}
+		if len(conditions) > 1 {
+			condition = parenthesize(condition, precedenceOr)
+		}
+		sb.WriteString(condition.Expr)
+		args = append(args, condition.Args...)
+	}
+	if len(keys) > 0 {
+		orderBy := b.translator.orderBy(query.Sort, keys)
+		sb.WriteString(" ORDER BY " + orderBy.Expr)
+		args = append(args, orderBy.Args...)
+	}
+	if query.Limit > 0 {
+		sb.WriteString(fmt.Sprintf(" LIMIT %d", query.Limit))
+	}
+	return SQLQuery(internal.BindArgs(sb.String(), b.translator.dialect.placeholder)), args, nil
+}
+
+// table formats the name of the table, optionally qualified by the schema
+func (b *queryBuilder) table(name string) (string, error) {
+	table, err := b.translator.table(name)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", ErrInvalidIdentifier, err)
+	}
+	return table, nil
+}
+
+// seek translates the condition on the rows following the row of the cursor in the sort order,
+// e.g. (a > 1) or ((a = 1) and (b < 'x')) for sorting by a and b descending
+func (b *queryBuilder) seek(sort []SortKey, keys []internal.TranslationResult, cursor string) (internal.TranslationResult, error) {
+	if len(keys) == 0 {
+		return internal.TranslationResult{}, fmt.Errorf("%w: pages are not sorted", ErrInvalidCursor)
+	}
+	values, err := b.decodeCursor(sort, keys, cursor)
+	if err != nil {
+		return internal.TranslationResult{}, err
+	}
+	var condition internal.TranslationResult
+	for i := len(keys) - 1; i >= 0; i-- {
+		after, err := b.follows(keys[i], values[i], sort[i].Desc)
+		if err != nil {
+			return internal.TranslationResult{}, err
+		}
+		if i < len(keys)-1 {
+			equal, err := b.binary("==", keys[i], values[i])
+			if err != nil {
+				return internal.TranslationResult{}, err
+			}
+			if equal, err = b.binary("and", equal, condition); err != nil {
+				return internal.TranslationResult{}, err
+			}
+			if after, err = b.binary("or", after, equal); err != nil {
+				return internal.TranslationResult{}, err
+			}
+		}
+		condition = after
+	}
+	return condition, nil
+}
+
+// follows translates the condition on the key following the value, where false follows true for descending order
+func (b *queryBuilder) follows(key, value internal.TranslationResult, desc bool) (internal.TranslationResult, error) {
+	if key.Type == internal.ExprTypeBoolIdentifier {
+		if value.Args[0].(bool) != desc {
+			return internal.TranslationResult{Expr: "FALSE", Type: internal.ExprTypeBool}, nil
+		}
+		return b.binary("==", key, internal.Literal(!desc, internal.ExprTypeBool))
+	}
+	if desc {
+		return b.binary("<", key, value)
+	}
+	return b.binary(">", key, value)
+}
+
+func (b *queryBuilder) binary(op string, left, right internal.TranslationResult) (internal.TranslationResult, error) {
+	return b.translator.translateBinaryOperator(&ast.BinaryNode{Operator: op}, left, right)
+}
+
+// decodeCursor decodes the values of the sort keys as literals of their types, failing for cursors of other sort keys
+func (b *queryBuilder) decodeCursor(sort []SortKey, keys []internal.TranslationResult, encoded string) ([]internal.TranslationResult, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded cursor
+	if err := decoder.Decode(&decoded); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidCursor, err)
+	}
+	if decoded.Sort != sortHash(sort) {
+		return nil, fmt.Errorf("%w: cursor of other sort keys", ErrInvalidCursor)
+	}
+	values := decoded.Values
+	if len(values) != len(keys) {
+		return nil, fmt.Errorf("%w: %d values for %d sort keys", ErrInvalidCursor, len(values), len(keys))
+	}
+	literals := make([]internal.TranslationResult, 0, len(values))
+	for i, value := range values {
+		literal, err := cursorLiteral(value, keys[i].Type)
+		if err != nil {
+			return nil, fmt.Errorf("%w: value %v: %w", ErrInvalidCursor, i, err)
+		}
+		literals = append(literals, literal)
+	}
+	return literals, nil
+}
+
+// cursorLiteral converts the decoded JSON value to a literal of the type of the sort key
+func cursorLiteral(value any, keyType internal.ExprType) (internal.TranslationResult, error) {
+	literalType, _ := internal.LiteralType(keyType)
+	switch v := value.(type) {
+	case json.Number:
+		switch keyType {
+		case internal.ExprTypeIntIdentifier:
+			i, err := v.Int64()
+			return internal.Literal(int(i), literalType), err
+		case internal.ExprTypeFloatIdentifier:
+			f, err := v.Float64()
+			return internal.Literal(f, literalType), err
+		}
+	case string:
+		switch keyType {
+		case internal.ExprTypeStringIdentifier:
+			return internal.Literal(v, literalType), nil
+		case internal.ExprTypeTimestampIdentifier:
+			t, err := time.Parse(time.RFC3339Nano, v)
+			return internal.Literal(t.UTC(), literalType), err
+		}
+	case bool:
+		if keyType == internal.ExprTypeBoolIdentifier {
+			return internal.Literal(v, literalType), nil
+		}
+	}
+	return internal.TranslationResult{}, fmt.Errorf("%v is not %v", value, keyType)
+}
@@ -0,0 +1,154 @@
+package filter_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/happening-oss/expr2sql/pkg/filter"
+)
+
+var _ = Describe("Query builder", func() {
+	identifiers := []filter.Identifier{
+		{ExprName: "id", Type: filter.IdentifierTypeInt},
+		{ExprName: "priority", Type: filter.IdentifierTypeInt},
+		{ExprName: "name", Type: filter.IdentifierTypeString, CaseInsensitive: true},
+		{ExprName: "pinned", Type: filter.IdentifierTypeBool},
+		{ExprName: "createdAt", DBName: "created_at", Type: filter.IdentifierTypeTimestamp},
+		{ExprName: "jsonField", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+			"score": filter.JSONLeaf(filter.IdentifierTypeFloat),
+			"tags":  filter.JSONArray(filter.JSONLeaf(filter.IdentifierTypeString)),
+		}},
+	}
+	var qb filter.QueryBuilder
+
+	BeforeEach(func() {
+		var err error
+		qb, err = filter.NewQueryBuilder(identifiers, filter.TranslatorDialectPostgres)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("builds the first page", func() {
+		query, args, err := qb.Build(filter.Query{
+			Table:  "app.tickets",
+			Filter: `priority > 2 and name contains "x"`,
+			Sort:   []filter.SortKey{{Expr: "jsonField.score", Desc: true}, {Expr: "id"}},
+			Limit:  20,
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(query).To(Equal(filter.SQLQuery(`SELECT * FROM app.tickets WHERE ((priority > $1) and (name ilike $2)) ORDER BY cast(jsonField ->> 'score' as float) DESC, id ASC LIMIT 20`)))
+		Expect(args).To(Equal([]any{2, "%x%"}))
+	})
+
+	It("builds the next page after the cursor", func() {
+		sort := []filter.SortKey{{Expr: "jsonField.score", Desc: true}, {Expr: "createdAt"}, {Expr: "name"}, {Expr: "id"}}
+		cursor, err := filter.EncodeCursor(sort, 1.5, time.Date(2024, 9, 17, 8, 0, 0, 0, time.UTC), "Ab", 7)
+		Expect(err).ToNot(HaveOccurred())
+
+		query, args, err := qb.Build(filter.Query{
+			With:   "recent AS (SELECT * FROM tickets WHERE created_at > now() - interval '1 day')",
+			Table:  "recent",
+			Filter: "priority > 2",
+			Sort:   sort,
+			After:  cursor,
+			Limit:  20,
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(query).To(Equal(filter.SQLQuery(`WITH recent AS (SELECT * FROM tickets WHERE created_at > now() - interval '1 day') SELECT * FROM recent ` +
+			`WHERE (priority > $1) AND ((cast(jsonField ->> 'score' as float) < $2) or ((cast(jsonField ->> 'score' as float) = $3) and ` +
+			`((created_at > $4) or ((created_at = $5) and ((name > $6) or ((name = $7) and (id > $8))))))) ` +
+			`ORDER BY cast(jsonField ->> 'score' as float) DESC, created_at ASC, name ASC, id ASC LIMIT 20`)))
+		ts := time.Date(2024, 9, 17, 8, 0, 0, 0, time.UTC)
+		Expect(args).To(Equal([]any{2, 1.5, 1.5, ts, ts, "Ab", "Ab", 7}))
+	})
+
+	It("pages by bool keys", func() {
+		sort := []filter.SortKey{{Expr: "pinned", Desc: true}, {Expr: "id"}}
+		cursor, err := filter.EncodeCursor(sort, true, 3)
+		Expect(err).ToNot(HaveOccurred())
+
+		query, args, err := qb.Build(filter.Query{
+			Table: "tickets",
+			Sort:  sort,
+			After: cursor,
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(query).To(Equal(filter.SQLQuery(`SELECT * FROM tickets WHERE ((pinned = $1) or ((pinned = $2) and (id > $3))) ORDER BY pinned DESC, id ASC`)))
+		Expect(args).To(Equal([]any{false, true, 3}))
+	})
+
+	It("quotes names for the dialect", func() {
+		qb, err := filter.NewQueryBuilder(identifiers, filter.TranslatorDialectMySQL)
+		Expect(err).ToNot(HaveOccurred())
+		sort := []filter.SortKey{{Expr: "id", Desc: true}}
+		cursor, err := filter.EncodeCursor(sort, 3)
+		Expect(err).ToNot(HaveOccurred())
+
+		query, args, err := qb.Build(filter.Query{Table: "order", Sort: sort, After: cursor, Limit: 5})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(query).To(Equal(filter.SQLQuery("SELECT * FROM `order` WHERE (`id` < ?) ORDER BY `id` DESC LIMIT 5")))
+		Expect(args).To(Equal([]any{3}))
+	})
+
+	It("builds simplified conditions", func() {
+		qb, err := filter.NewQueryBuilder(identifiers, filter.TranslatorDialectPostgres, filter.WithSimplifiedSQL())
+		Expect(err).ToNot(HaveOccurred())
+		sort := []filter.SortKey{{Expr: "priority"}, {Expr: "id"}}
+		cursor, err := filter.EncodeCursor(sort, 2, 7)
+		Expect(err).ToNot(HaveOccurred())
+
+		query, args, err := qb.Build(filter.Query{
+			Table:  "tickets",
+			Filter: `priority > 1 + 1 or pinned`,
+			Sort:   sort,
+			After:  cursor,
+		})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(query).To(Equal(filter.SQLQuery(`SELECT * FROM tickets WHERE (priority > $1 or pinned) AND (priority > $2 or priority = $3 and id > $4) ORDER BY priority ASC, id ASC`)))
+		Expect(args).To(Equal([]any{2, 2, 2, 7}))
+	})
+
+	It("fails for sort keys which are not sortable", func() {
+		for _, key := range []string{"jsonField", "jsonField.tags", "priority + 1", "unknown"} {
+			_, _, err := qb.Build(filter.Query{Table: "tickets", Sort: []filter.SortKey{{Expr: key}}})
+
+			Expect(err).To(HaveOccurred(), key)
+			Expect(filter.IsUnsupportedOperation(err) || filter.IsUnknownIdentifier(err)).To(BeTrue(), key)
+		}
+	})
+
+	It("fails for invalid cursors", func() {
+		sort := []filter.SortKey{{Expr: "id"}}
+		mismatched, err := filter.EncodeCursor(sort, "x")
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, cursor := range []string{"not base64!", mismatched, "W10"} {
+			_, _, err := qb.Build(filter.Query{Table: "tickets", Sort: sort, After: cursor})
+
+			Expect(err).To(MatchError(filter.ErrInvalidCursor), cursor)
+		}
+	})
+
+	It("fails for cursors of other sort keys", func() {
+		cursor, err := filter.EncodeCursor([]filter.SortKey{{Expr: "id"}}, 3)
+		Expect(err).ToNot(HaveOccurred())
+
+		for _, sort := range [][]filter.SortKey{{{Expr: "id", Desc: true}}, {{Expr: "priority"}}} {
+			_, _, err := qb.Build(filter.Query{Table: "tickets", Sort: sort, After: cursor})
+
+			Expect(err).To(MatchError(filter.ErrInvalidCursor), sort[0].Expr)
+		}
+	})
+
+	It("fails for invalid table names", func() {
+		_, _, err := qb.Build(filter.Query{Table: "tickets; drop table users"})
+
+		Expect(err).To(MatchError(filter.ErrInvalidIdentifier))
+	})
+})
@@ -0,0 +1,142 @@
+package filter
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/expr-lang/expr/ast"
+
+	"github.com/happening-oss/expr2sql/pkg/filter/internal"
+)
+
+// relationElement is the spec of relation identifiers, which is passed along their translation like JSON specs
+type relationElement struct {
+	relation *Relation
+	column   internal.TranslationResult // column of the row equal to the column of the foreign table
+	alias    string                     // alias of the foreign table within the predicate over the related rows, empty otherwise
+}
+
+func (r relationElement) IdentifierType() IdentifierType {
+	if r.relation.Many {
+		return IdentifierType(internal.ArrayOf(internal.ExprTypeRelation))
+	}
+	return IdentifierTypeRelation
+}
+
+// relationJoin is a foreign table of a to-one relation, whose column is selected by a correlated subquery
+type relationJoin struct {
+	table     string
+	condition internal.TranslationResult
+}
+
+// relationPredicates are templates of the array predicate builtins over related rows, with the foreign table as {0},
+// the join condition as {1} and the condition on a related row as {2}
+var relationPredicates = map[string]string{
+	"any":  "(EXISTS (SELECT 1 FROM {0} WHERE {1} AND {2}))",
+	"all":  "(NOT EXISTS (SELECT 1 FROM {0} WHERE {1} AND {2} IS NOT TRUE))",
+	"none": "(NOT EXISTS (SELECT 1 FROM {0} WHERE {1} AND {2}))",
+	"one":  "((SELECT count(*) FROM {0} WHERE {1} AND {2}) = 1)",
+}
+
+// table formats the name of the table, optionally qualified by the schema
+func (t *translator) table(name string) (string, error) {
+	parts, err := tableName(name)
+	if err != nil {
+		return "", err
+	}
+	for i, part := range parts {
+		parts[i] = t.dialect.identifier(part)
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// join formats the foreign table of the relation named by the alias, along with the condition on its rows
+// being related to the row
+func (t *translator) join(related relationElement, alias string) (internal.TranslationResult, internal.TranslationResult, error) {
+	table, err := t.table(related.relation.Table)
+	if err != nil {
+		return internal.TranslationResult{}, internal.TranslationResult{}, err
+	}
+	column := internal.TranslationResult{Expr: t.dialect.identifier(alias) + "." + t.dialect.identifier(related.relation.Column)}
+	return internal.TranslationResult{Expr: table + " AS " + t.dialect.identifier(alias)},
+		internal.FormatTemplate("{0} = {1}", column, related.column), nil
+}
+
+// translateRelated translates the property of the related row to the column of the foreign table, qualified by its
+// alias. Single related rows are joined by correlated subqueries, which wrap the translated property.
+func (t *translator) translateRelated(node *ast.MemberNode, path jsonPath, related relationElement) (jsonPath, JSONElement, error) {
+	if related.relation.Many && related.alias == "" {
+		return jsonPath{}, nil, unsupportedOperation(node, fmt.Sprintf("'%v' has many related rows, which need an array predicate", node.Node))
+	}
+	property, ok := node.Property.(*ast.StringNode)
+	if !ok {
+		return jsonPath{}, nil, unsupportedOperation(node.Property, fmt.Sprintf("relation column needs to be a name, instead found %v", node.Property))
+	}
+	index := slices.IndexFunc(related.relation.Identifiers, func(id Identifier) bool {
+		return id.ExprName == property.Value
+	})
+	if index == -1 {
+		return jsonPath{}, nil, unknownIdentifier(node, fmt.Sprintf("relation '%v' does not contain field '%v'", node.Node, property.Value))
+	}
+	alias := related.alias
+	if alias == "" {
+		alias = fmt.Sprintf("rel%d", t.depth+len(path.joins)+1)
+		table, condition, err := t.join(related, alias)
+		if err != nil {
+			return jsonPath{}, nil, unsupportedOperation(node, err.Error())
+		}
+		path.joins = append(slices.Clone(path.joins), relationJoin{table: table.Expr, condition: condition})
+	}
+	identifier := related.relation.Identifiers[index]
+	identifier.Table = alias
+	column, jsonEl := t.translateColumn(identifier)
+	path.column, path.keys = column, nil
+	return path, jsonEl, nil
+}
+
+// translateRelationPredicate translates the array predicate over the rows related to the row to a subquery
+// of the foreign table, with the closure translated by a copy of the translator referencing the related row as #
+func (t *translator) translateRelationPredicate(node *ast.BuiltinNode, closure *ast.ClosureNode, related relationElement) (internal.TranslationResult, JSONElement, error) {
+	template, ok := relationPredicates[node.Name]
+	if !ok || !related.relation.Many {
+		return internal.TranslationResult{}, nil, unsupportedOperation(node, fmt.Sprintf("%v of relation %v", node.Name, node.Arguments[0]))
+	}
+	scoped := *t
+	scoped.depth++
+	scoped.relatedRows = true
+	alias := fmt.Sprintf("rel%d", scoped.depth)
+	table, condition, err := t.join(related, alias)
+	if err != nil {
+		return internal.TranslationResult{}, nil, unsupportedOperation(node, err.Error())
+	}
+	scoped.element = &internal.TranslationResult{Expr: t.dialect.identifier(alias), Type: internal.ExprTypeRelation}
+	scoped.elementSpec = relationElement{relation: related.relation, alias: alias}
+	scoped.recordFilter(closure.Node)
+	rowCondition, err := scoped.translate(closure.Node)
+	if err != nil {
+		return internal.TranslationResult{}, nil, err
+	}
+	if rowCondition.Type != internal.ExprTypeBool && rowCondition.Type != internal.ExprTypeBoolIdentifier {
+		return internal.TranslationResult{}, nil, unsupportedOperation(closure.Node, fmt.Sprintf("non-boolean %v condition %v", node.Name, closure.Node)).
+			withTypes([][]internal.ExprType{{internal.ExprTypeBool}, {internal.ExprTypeBoolIdentifier}}, rowCondition.Type)
+	}
+	if node.Name == "all" {
+		rowCondition = parenthesize(rowCondition, precedenceComparison) // the condition precedes IS NOT TRUE
+	} else {
+		rowCondition = parenthesize(rowCondition, precedenceOr) // the condition follows the join condition within AND
+	}
+	result := internal.FormatTemplate(template, table, condition, rowCondition)
+	result.Type = internal.ExprTypeBool
+	return result, nil, nil
+}
+
+// correlate wraps the expression of the related row in the correlated subqueries of the joins, from the innermost one
+func correlate(expr internal.TranslationResult, joins []relationJoin) internal.TranslationResult {
+	for i := len(joins) - 1; i >= 0; i-- {
+		correlated := internal.FormatTemplate("(SELECT {0} FROM {1} WHERE {2})", expr, internal.TranslationResult{Expr: joins[i].table}, joins[i].condition)
+		correlated.Type, correlated.CaseInsensitive = expr.Type, expr.CaseInsensitive
+		expr = correlated
+	}
+	return expr
+}
@@ -0,0 +1,203 @@
+package filter
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/expr-lang/expr/ast"
+)
+
+// simplify folds operations on constants, and removes redundant terms of boolean operators and double negations,
+// which keeps the result of the condition by three-valued logic. The simplified tree shares the unchanged nodes
+// with the tree, which is left intact.
+func simplify(node ast.Node) ast.Node {
+	switch typed := node.(type) {
+	case *ast.BinaryNode:
+		left, right := simplify(typed.Left), simplify(typed.Right)
+		if folded, ok := foldBinary(typed.Operator, left, right); ok {
+			return folded
+		}
+		if left == typed.Left && right == typed.Right {
+			return node
+		}
+		simplified := *typed
+		simplified.Left, simplified.Right = left, right
+		return &simplified
+	case *ast.UnaryNode:
+		nested := simplify(typed.Node)
+		if typed.Operator == "not" || typed.Operator == "!" {
+			if b, ok := nested.(*ast.BoolNode); ok {
+				return &ast.BoolNode{Value: !b.Value}
+			}
+			if negated, ok := nested.(*ast.UnaryNode); ok && (negated.Operator == "not" || negated.Operator == "!") {
+				return negated.Node
+			}
+		}
+		if typed.Operator == "-" {
+			switch number := nested.(type) {
+			case *ast.IntegerNode:
+				return &ast.IntegerNode{Value: -number.Value}
+			case *ast.FloatNode:
+				return &ast.FloatNode{Value: -number.Value}
+			}
+		}
+		if nested == typed.Node {
+			return node
+		}
+		simplified := *typed
+		simplified.Node = nested
+		return &simplified
+	case *ast.ConditionalNode:
+		simplified := *typed
+		simplified.Cond, simplified.Exp1, simplified.Exp2 = simplify(typed.Cond), simplify(typed.Exp1), simplify(typed.Exp2)
+		return &simplified
+	case *ast.CallNode:
+		simplified := *typed
+		simplified.Arguments = simplifyAll(typed.Arguments)
+		return &simplified
+	case *ast.BuiltinNode:
+		simplified := *typed
+		simplified.Arguments = simplifyAll(typed.Arguments)
+		return &simplified
+	case *ast.ClosureNode:
+		simplified := *typed
+		simplified.Node = simplify(typed.Node)
+		return &simplified
+	case *ast.ArrayNode:
+		simplified := *typed
+		simplified.Nodes = simplifyAll(typed.Nodes)
+		return &simplified
+	default:
+		return node
+	}
+}
+
+func simplifyAll(nodes []ast.Node) []ast.Node {
+	simplified := make([]ast.Node, 0, len(nodes))
+	for _, node := range nodes {
+		simplified = append(simplified, simplify(node))
+	}
+	return simplified
+}
+
+// foldBinary folds the boolean operator with a constant operand, or the operator of constant numbers or booleans.
+// Strings are not compared, as they are compared case-insensitively or chronologically by some translators.
+func foldBinary(op string, left, right ast.Node) (ast.Node, bool) {
+	leftBool, leftIsBool := left.(*ast.BoolNode)
+	rightBool, rightIsBool := right.(*ast.BoolNode)
+	switch op {
+	case "and", "&&", "or", "||":
+		absorbing := op == "or" || op == "||" // true or x is true, while false and x is false
+		switch {
+		case leftIsBool && leftBool.Value == absorbing, rightIsBool && rightBool.Value == absorbing:
+			return &ast.BoolNode{Value: absorbing}, true
+		case leftIsBool:
+			return right, true
+		case rightIsBool:
+			return left, true
+		}
+		return nil, false
+	case "==", "!=":
+		if leftIsBool && rightIsBool {
+			return &ast.BoolNode{Value: (leftBool.Value == rightBool.Value) == (op == "==")}, true
+		}
+	}
+	leftInt, leftIsInt := left.(*ast.IntegerNode)
+	rightInt, rightIsInt := right.(*ast.IntegerNode)
+	if leftIsInt && rightIsInt {
+		return foldInts(op, leftInt.Value, rightInt.Value)
+	}
+	leftFloat, leftIsNumber := constantFloat(left)
+	rightFloat, rightIsNumber := constantFloat(right)
+	if leftIsNumber && rightIsNumber {
+		return foldFloats(op, leftFloat, rightFloat)
+	}
+	return nil, false
+}
+
+// foldInts folds the operator of integers, except for division, which truncates in some dialects only,
+// and operations overflowing 64 bits
+func foldInts(op string, left, right int) (ast.Node, bool) {
+	var result *big.Int
+	switch op {
+	case "+":
+		result = new(big.Int).Add(big.NewInt(int64(left)), big.NewInt(int64(right)))
+	case "-":
+		result = new(big.Int).Sub(big.NewInt(int64(left)), big.NewInt(int64(right)))
+	case "*":
+		result = new(big.Int).Mul(big.NewInt(int64(left)), big.NewInt(int64(right)))
+	case "%":
+		if right == 0 {
+			return nil, false
+		}
+		result = new(big.Int).Rem(big.NewInt(int64(left)), big.NewInt(int64(right))) // sign of the dividend, as in SQL
+	case "/":
+		return nil, false
+	case "==":
+		return &ast.BoolNode{Value: left == right}, true
+	case "!=":
+		return &ast.BoolNode{Value: left != right}, true
+	case "<":
+		return &ast.BoolNode{Value: left < right}, true
+	case ">":
+		return &ast.BoolNode{Value: left > right}, true
+	case "<=":
+		return &ast.BoolNode{Value: left <= right}, true
+	case ">=":
+		return &ast.BoolNode{Value: left >= right}, true
+	default:
+		return nil, false
+	}
+	if !result.IsInt64() {
+		return nil, false
+	}
+	return &ast.IntegerNode{Value: int(result.Int64())}, true
+}
+
+// foldFloats folds the operator of numbers, one of which is a float, except for arithmetic overflowing
+// to infinity, which has no SQL literal
+func foldFloats(op string, left, right float64) (ast.Node, bool) {
+	var result float64
+	switch op {
+	case "+":
+		result = left + right
+	case "-":
+		result = left - right
+	case "*":
+		result = left * right
+	case "/":
+		if right == 0 {
+			return nil, false
+		}
+		result = left / right
+	case "==":
+		return &ast.BoolNode{Value: left == right}, true
+	case "!=":
+		return &ast.BoolNode{Value: left != right}, true
+	case "<":
+		return &ast.BoolNode{Value: left < right}, true
+	case ">":
+		return &ast.BoolNode{Value: left > right}, true
+	case "<=":
+		return &ast.BoolNode{Value: left <= right}, true
+	case ">=":
+		return &ast.BoolNode{Value: left >= right}, true
+	default:
+		return nil, false
+	}
+	if math.IsInf(result, 0) || math.IsNaN(result) {
+		return nil, false
+	}
+	return &ast.FloatNode{Value: result}, true
+}
+
+func constantFloat(node ast.Node) (float64, bool) {
+	switch typed := node.(type) {
+	case *ast.IntegerNode:
+		return float64(typed.Value), true
+	case *ast.FloatNode:
+		return typed.Value, true
+	default:
+		return 0, false
+	}
+}
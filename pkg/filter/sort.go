@@ -0,0 +1,210 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/file"
+	"github.com/expr-lang/expr/parser"
+
+	"github.com/happening-oss/expr2sql/pkg/filter/internal"
+)
+
+// SortKey orders rows by the identifier or JSON property, e.g. createdAt or jsonField.score
+type SortKey struct {
+	Expr  string
+	Desc  bool
+	Nulls SortNulls
+}
+
+// SortNulls is the position of NULL values, which is up to the database by default
+type SortNulls byte
+
+const (
+	SortNullsDefault SortNulls = iota
+	SortNullsFirst
+	SortNullsLast
+)
+
+type SQLOrderBy string
+
+// ParseSort parses comma-separated sort keys, each an identifier or JSON property optionally followed by asc or desc,
+// and by nulls first or nulls last, e.g. "priority desc, createdAt, jsonField.score desc nulls last"
+func ParseSort(sort string) ([]SortKey, error) {
+	items, err := parseSort(sort)
+	if err != nil {
+		return nil, bindSource(err, sort)
+	}
+	keys := make([]SortKey, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, item.SortKey)
+	}
+	return keys, nil
+}
+
+// sortItem is a sort key along with the offset of its expression within the sort string
+type sortItem struct {
+	SortKey
+	offset int
+}
+
+// listItem is an item of a comma-separated list along with its offset within the list
+type listItem struct {
+	text   string
+	offset int
+}
+
+// splitList splits the list on the commas which are outside of quotes, parentheses and brackets
+func splitList(list string) []listItem {
+	var items []listItem
+	start, depth := 0, 0
+	var quote byte
+	for i := 0; i < len(list); i++ {
+		c := list[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'' || c == '`':
+			quote = c
+		case c == '(' || c == '[':
+			depth++
+		case c == ')' || c == ']':
+			depth--
+		case c == ',' && depth == 0:
+			items = append(items, listItem{text: list[start:i], offset: start})
+			start = i + 1
+		}
+	}
+	return append(items, listItem{text: list[start:], offset: start})
+}
+
+// listExpr returns the expression which is the beginning of the item up to the end, along with its offset within the list,
+// failing with the message if it is empty
+func listExpr(list string, item listItem, end int, message string) (string, int, error) {
+	expr := strings.TrimLeft(item.text[:end], " \t\r\n")
+	offset := item.offset + end - len(expr)
+	if expr == "" {
+		location := utf8.RuneCountInString(list[:offset])
+		return "", 0, parsingError(&file.Error{Location: file.Location{From: location, To: location}, Message: message}, list)
+	}
+	return expr, offset, nil
+}
+
+var sortModifiers = regexp.MustCompile(`(?i)(?:\s+(asc|desc))?(?:\s+nulls\s+(first|last))?\s*$`)
+
+func parseSort(sort string) ([]sortItem, error) {
+	var items []sortItem
+	for _, item := range splitList(sort) {
+		modifiers := sortModifiers.FindStringSubmatchIndex(item.text)
+		expr, offset, err := listExpr(sort, item, modifiers[0], "missing sort key")
+		if err != nil {
+			return nil, err
+		}
+		key := SortKey{Expr: expr}
+		if modifiers[2] != -1 {
+			key.Desc = strings.EqualFold(item.text[modifiers[2]:modifiers[3]], "desc")
+		}
+		if modifiers[4] != -1 {
+			key.Nulls = SortNullsLast
+			if strings.EqualFold(item.text[modifiers[4]:modifiers[5]], "first") {
+				key.Nulls = SortNullsFirst
+			}
+		}
+		items = append(items, sortItem{SortKey: key, offset: offset})
+	}
+	return items, nil
+}
+
+func (t *translator) TranslateOrderBy(sort string) (SQLOrderBy, error) {
+	items, err := parseSort(sort)
+	if err != nil {
+		return "", bindSource(err, sort)
+	}
+	sortKeys := make([]SortKey, 0, len(items))
+	keys := make([]internal.TranslationResult, 0, len(items))
+	for _, item := range items {
+		key, err := t.translateKey(sort, item.offset, item.Expr, "sorting by")
+		if err != nil {
+			return "", err
+		}
+		sortKeys = append(sortKeys, item.SortKey)
+		keys = append(keys, key)
+	}
+	return SQLOrderBy(t.inline(t.orderBy(sortKeys, keys))), nil
+}
+
+// sortableTypes are the types of identifiers and JSON properties which rows can be ordered and grouped by
+var sortableTypes = []internal.ExprType{
+	internal.ExprTypeIntIdentifier,
+	internal.ExprTypeFloatIdentifier,
+	internal.ExprTypeBoolIdentifier,
+	internal.ExprTypeStringIdentifier,
+	internal.ExprTypeTimestampIdentifier,
+}
+
+// translateKey translates the expression at the offset within the source, which needs to be an identifier
+// or a JSON property of a sortable type, where the operation describes the use of the key in errors
+func (t *translator) translateKey(source string, offset int, expr string, operation string) (internal.TranslationResult, error) {
+	// the expression is parsed at its offset, so that errors are located within the source
+	padding := strings.Map(func(r rune) rune {
+		if r == '\n' {
+			return r
+		}
+		return ' '
+	}, source[:offset])
+	parsed, err := parser.Parse(padding + expr)
+	if err != nil {
+		return internal.TranslationResult{}, bindSource(parsingError(err, padding+expr), source)
+	}
+	switch parsed.Node.(type) {
+	case *ast.IdentifierNode, *ast.MemberNode:
+	default:
+		return internal.TranslationResult{}, bindSource(unsupportedOperation(parsed.Node, fmt.Sprintf("%v %v", operation, parsed.Node)), source)
+	}
+	result, err := t.translate(parsed.Node)
+	if err != nil {
+		return internal.TranslationResult{}, bindSource(err, source)
+	}
+	if !slices.Contains(sortableTypes, result.Type) {
+		err := unsupportedOperation(parsed.Node, fmt.Sprintf("%v %v", operation, parsed.Node)).withTypes([][]internal.ExprType{sortableTypes}, result.Type)
+		return internal.TranslationResult{}, bindSource(err, source)
+	}
+	result.CaseInsensitive = false // rows are ordered case-sensitively
+	return result, nil
+}
+
+// standardOrderBy formats the ORDER BY item in standard SQL, e.g. {0} DESC NULLS LAST
+func standardOrderBy(desc bool, nulls SortNulls) string {
+	item := "{0} ASC"
+	if desc {
+		item = "{0} DESC"
+	}
+	switch nulls {
+	case SortNullsFirst:
+		item += " NULLS FIRST"
+	case SortNullsLast:
+		item += " NULLS LAST"
+	}
+	return item
+}
+
+// orderBy formats the translated sort keys as the items of the ORDER BY clause
+func (t *translator) orderBy(sort []SortKey, keys []internal.TranslationResult) internal.TranslationResult {
+	var result internal.TranslationResult
+	items := make([]string, 0, len(keys))
+	for i, key := range keys {
+		item := internal.FormatTemplate(t.dialect.orderBy(sort[i].Desc, sort[i].Nulls), key)
+		items = append(items, item.Expr)
+		result.Args = append(result.Args, item.Args...)
+	}
+	result.Expr = strings.Join(items, ", ")
+	return result
+}
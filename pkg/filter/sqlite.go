@@ -0,0 +1,235 @@
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/happening-oss/expr2sql/pkg/filter/internal"
+)
+
+// sqliteBinaryOperators requires SQLite 3.42+ for julianday 'auto' modifier and math functions.
+// The matches operator relies on the REGEXP user function, which has to be registered on the connection.
+var sqliteBinaryOperators = map[string]internal.BinaryOperatorDescriptor{
+	"**": internal.NumericFunctionOperatorDescriptor("pow"),
+	"^":  internal.NumericFunctionOperatorDescriptor("pow"),
+
+	"in": internal.CombinedOperatorDescriptor(
+		internal.MembershipOperatorDescriptor("IN", false),
+		internal.ArrayMembershipOperatorDescriptor("{0} IN (SELECT value FROM json_each({1}))"),
+		internal.JSONArrayMembershipOperatorDescriptor("{0} IN (SELECT value FROM json_each({1}))"),
+	),
+	"not in": internal.CombinedOperatorDescriptor(
+		internal.MembershipOperatorDescriptor("NOT IN", true),
+		internal.ArrayMembershipOperatorDescriptor("{0} NOT IN (SELECT value FROM json_each({1}))"),
+		internal.JSONArrayMembershipOperatorDescriptor("{0} NOT IN (SELECT value FROM json_each({1}))"),
+	),
+
+	"+": internal.CombinedOperatorDescriptor(internal.NumericOperatorDescriptor("+"), internal.TemporalOperatorDescriptor("+", sqliteTemporal("+"))),
+	"-": internal.CombinedOperatorDescriptor(internal.NumericOperatorDescriptor("-"), internal.TemporalOperatorDescriptor("-", sqliteTemporal("-"))),
+
+	"==": sqliteTimestampComparison(internal.NillableComparisonOperatorDescriptor("=", "IS")),
+	"!=": sqliteTimestampComparison(internal.NillableComparisonOperatorDescriptor("<>", "IS NOT")),
+	"<":  sqliteTimestampComparison(internal.ComparisonOperatorDescriptor("<")),
+	">":  sqliteTimestampComparison(internal.ComparisonOperatorDescriptor(">")),
+	"<=": sqliteTimestampComparison(internal.ComparisonOperatorDescriptor("<=")),
+	">=": sqliteTimestampComparison(internal.ComparisonOperatorDescriptor(">=")),
+
+	"contains":   internal.GlobOperatorDescriptor("*", "*"),
+	"startsWith": internal.GlobOperatorDescriptor("", "*"),
+	"endsWith":   internal.GlobOperatorDescriptor("*", ""),
+	"matches":    internal.RegexOperatorDescriptor("REGEXP"),
+}
+
+// sqliteTimestampComparison compares timestamps as julian days, since SQLite stores them either
+// as text in various ISO-8601 formats or as unix epoch numbers
+func sqliteTimestampComparison(descriptor internal.BinaryOperatorDescriptor) internal.BinaryOperatorDescriptor {
+	opTranslator := descriptor.OpTranslator
+	descriptor.OpTranslator = func(left, right internal.TranslationResult) internal.TranslationResult {
+		if left.Type == internal.ExprTypeNil || right.Type == internal.ExprTypeNil {
+			return opTranslator(left, right)
+		}
+		return opTranslator(sqliteJulianDay(left), sqliteJulianDay(right))
+	}
+	return descriptor
+}
+
+func sqliteJulianDay(result internal.TranslationResult) internal.TranslationResult {
+	if result.Type == internal.ExprTypeTimestamp || result.Type == internal.ExprTypeTimestampIdentifier {
+		result.Expr = fmt.Sprintf("julianday(%v, 'auto')", result.Expr)
+	}
+	return result
+}
+
+// sqliteTemporal adds and subtracts timestamps as julian days and durations, which are numbers of seconds,
+// resulting in julian days for timestamps
+func sqliteTemporal(op string) func(left, right internal.TranslationResult) internal.TranslationResult {
+	return func(left, right internal.TranslationResult) internal.TranslationResult {
+		left, right = sqliteJulianDay(left), sqliteJulianDay(right)
+		switch {
+		case internal.IsTimestamp(left.Type) && internal.IsTimestamp(right.Type):
+			return internal.FormatTemplate("({0} - {1}) * 86400.0", left, right)
+		case internal.IsTimestamp(left.Type):
+			return internal.FormatTemplate("{0} "+op+" {1} / 86400.0", left, right)
+		case internal.IsTimestamp(right.Type):
+			return internal.FormatTemplate("{0} / 86400.0 + {1}", left, right)
+		default:
+			return internal.FormatTemplate("{0} "+op+" {1}", left, right)
+		}
+	}
+}
+
+// sqliteCaseInsensitiveBinaryOperators rely on LIKE, which ignores case of ASCII characters in SQLite
+var sqliteCaseInsensitiveBinaryOperators = map[string]internal.BinaryOperatorDescriptor{
+	"contains":   internal.LikeOperatorDescriptor(`%v LIKE %v ESCAPE '\'`, "%", "%"),
+	"startsWith": internal.LikeOperatorDescriptor(`%v LIKE %v ESCAPE '\'`, "", "%"),
+	"endsWith":   internal.LikeOperatorDescriptor(`%v LIKE %v ESCAPE '\'`, "%", ""),
+}
+
+// sqliteFunctions keep timestamps as they are stored, since they are compared as julian days.
+// Durations are numbers of seconds.
+var sqliteFunctions = map[string]internal.FunctionDescriptor{
+	"len": internal.CombinedFunctionDescriptor(
+		internal.LengthFunctionDescriptor("length({0})"),
+		internal.ArrayLengthFunctionDescriptor("json_array_length({0})"),
+		internal.JSONArrayLengthFunctionDescriptor("json_array_length({0})"),
+	),
+	"round": internal.IntegerNumericFunctionDescriptor("round({0})", "cast({0} as integer)"), // round results in a real for integers
+	"date":  internal.DateFunctionDescriptor("{0}", "date({0}, 'auto')"),
+	"duration": internal.DurationFunctionDescriptor(func(d time.Duration) internal.TranslationResult {
+		return internal.TranslationResult{Expr: "?", Args: []any{d.Seconds()}}
+	}),
+}
+
+var sqliteDateParts = map[string]internal.FunctionDescriptor{
+	"Year":    sqliteDatePart("%Y"),
+	"Month":   sqliteDatePart("%m"),
+	"Day":     sqliteDatePart("%d"),
+	"Hour":    sqliteDatePart("%H"),
+	"Minute":  sqliteDatePart("%M"),
+	"Second":  sqliteDatePart("%S"),
+	"Weekday": sqliteDatePart("%w"),
+	"YearDay": sqliteDatePart("%j"),
+}
+
+// sqliteDatePart formats the part of the timestamp, stored either as text or as a number, and converts it to an integer
+func sqliteDatePart(format string) internal.FunctionDescriptor {
+	return internal.DatePartFunctionDescriptor("cast(strftime('" + format + "', {0}, 'auto') as integer)")
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) operatorOverrides() map[string]internal.BinaryOperatorDescriptor {
+	return sqliteBinaryOperators
+}
+
+func (sqliteDialect) caseInsensitiveOperators() map[string]internal.BinaryOperatorDescriptor {
+	return sqliteCaseInsensitiveBinaryOperators
+}
+
+func (sqliteDialect) functionOverrides() map[string]internal.FunctionDescriptor {
+	return sqliteFunctions
+}
+
+func (sqliteDialect) dateParts() map[string]internal.FunctionDescriptor {
+	return sqliteDateParts
+}
+
+// sqlitePlainIdentifier matches names which need no quotes, as SQLite ignores the case of names
+var sqlitePlainIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// sqliteKeywords cannot be column names without quotes
+var sqliteKeywords = strings.Fields(`abort action add after all alter always analyze and as asc attach autoincrement before
+	begin between by cascade case cast check collate column commit conflict constraint create cross current current_date
+	current_time current_timestamp database default deferrable deferred delete desc detach distinct do drop each else end
+	escape except exclude exclusive exists explain fail filter first following for foreign from full generated glob group
+	groups having if ignore immediate in index indexed initially inner insert instead intersect into is isnull join key
+	last left like limit match materialized natural no not nothing notnull null nulls of offset on or order others outer
+	over partition plan pragma preceding primary query raise range recursive references regexp reindex release rename
+	replace restrict returning right rollback row rows savepoint select set table temp temporary then ties to transaction
+	trigger unbounded union unique update using vacuum values view virtual when where window with without`)
+
+// identifier quotes names which are keywords or contain special characters
+func (sqliteDialect) identifier(name string) string {
+	if sqlitePlainIdentifier.MatchString(name) && !slices.Contains(sqliteKeywords, strings.ToLower(name)) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+var sqlitePrimitiveTypeCast = map[internal.ExprType]string{
+	internal.ExprTypeIntIdentifier:   "integer",
+	internal.ExprTypeFloatIdentifier: "real",
+}
+
+func (d sqliteDialect) jsonExpr(column string, path []any, exprType internal.ExprType) string {
+	extract := fmt.Sprintf("json_extract(%v, %v)", column, d.quote(jsonPathExpression(path)))
+	if t, ok := sqlitePrimitiveTypeCast[exprType]; ok {
+		return fmt.Sprintf("cast(%v as %v)", extract, t)
+	}
+	return extract
+}
+
+// jsonTimestamp keeps RFC3339 timestamps, which are compared as julian days, and formats epoch timestamps as text
+func (sqliteDialect) jsonTimestamp(value string, format JSONTimestampFormat) string {
+	switch format {
+	case JSONTimestampFormatEpochSeconds:
+		return fmt.Sprintf("datetime(%v, 'unixepoch', 'subsec')", value)
+	case JSONTimestampFormatEpochMillis:
+		return fmt.Sprintf("datetime(%v / 1000.0, 'unixepoch', 'subsec')", value)
+	default:
+		return value
+	}
+}
+
+func (sqliteDialect) jsonCondition(internal.TranslationResult, []any, string, any) (internal.TranslationResult, bool) {
+	return internal.TranslationResult{}, false
+}
+
+func (sqliteDialect) arrayElements(array string, _ internal.ExprType, alias string) (string, string) {
+	return fmt.Sprintf("json_each(%v) AS %v", array, alias), alias + ".value"
+}
+
+func (sqliteDialect) arrayAggregate(elem string) string {
+	return fmt.Sprintf("json_group_array(%v)", elem)
+}
+
+func (d sqliteDialect) jsonArrayElements(array string, elemType internal.ExprType, alias string) (string, string) {
+	return d.arrayElements(array, elemType, alias)
+}
+
+func (d sqliteDialect) jsonArrayAggregate(elem string) string {
+	return d.arrayAggregate(elem)
+}
+
+func (d sqliteDialect) quote(s string) string {
+	return internal.EscapePlaceholders(d.literal(s))
+}
+
+func (sqliteDialect) orderBy(desc bool, nulls SortNulls) string {
+	return standardOrderBy(desc, nulls)
+}
+
+func (d sqliteDialect) literal(value any) string {
+	switch v := value.(type) {
+	case string:
+		return fmt.Sprintf(`'%v'`, strings.ReplaceAll(v, "'", "''"))
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'G', -1, 64)
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v))
+	case time.Time:
+		return d.literal(v.Format(time.RFC3339Nano))
+	default:
+		return d.literal(fmt.Sprint(v))
+	}
+}
+
+func (sqliteDialect) placeholder(int) string {
+	return "?"
+}
@@ -0,0 +1,252 @@
+package filter_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/happening-oss/expr2sql/pkg/filter"
+)
+
+var _ = Describe("SQLite translator", func() {
+	var trs filter.Translator
+
+	BeforeEach(func() {
+		trs = newTranslator([]filter.Identifier{
+			{ExprName: "intField", Type: filter.IdentifierTypeInt},
+			{ExprName: "floatField", Type: filter.IdentifierTypeFloat},
+			{ExprName: "boolField", Type: filter.IdentifierTypeBool},
+			{ExprName: "stringField", Type: filter.IdentifierTypeString},
+			{ExprName: "tsField", Type: filter.IdentifierTypeTimestamp, DBName: "ts_field"},
+			{ExprName: "jsonField", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+				"nestedProperty1": filter.JSONTree{
+					"stringProperty": filter.JSONLeaf(filter.IdentifierTypeString),
+				},
+				"intProperty":   filter.JSONLeaf(filter.IdentifierTypeInt),
+				"floatProperty": filter.JSONLeaf(filter.IdentifierTypeFloat),
+				"boolProperty":  filter.JSONLeaf(filter.IdentifierTypeBool),
+				"tsProperty":    filter.JSONLeaf(filter.IdentifierTypeTimestamp),
+			}},
+		}, filter.TranslatorDialectSQLite)
+	})
+
+	Describe("identifier translation", func() {
+		It("translates primitive types", func() {
+			query, err := trs.Translate(`intField == 2 and floatField < 1.5 and stringField != "ab'cd" and boolField == false`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((((intField = 2) and (floatField < 1.5)) and (stringField <> 'ab''cd')) and (boolField = FALSE))")))
+		})
+
+		It("quotes keywords", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "key", Type: filter.IdentifierTypeString},
+				{ExprName: "groupName", DBName: "Group", Table: "g", Type: filter.IdentifierTypeString},
+			}, filter.TranslatorDialectSQLite)
+
+			query, err := trs.Translate(`key == "a" and groupName == "b"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`(("key" = 'a') and (g."Group" = 'b'))`)))
+		})
+
+		It("translates nil", func() {
+			query, err := trs.Translate("tsField == nil")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(ts_field IS NULL)")))
+		})
+
+		It("translates timestamp", func() {
+			query, err := trs.Translate(`tsField < "2024-09-17T08:00:00+03:00"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(julianday(ts_field, 'auto') < julianday('2024-09-17T05:00:00Z', 'auto'))")))
+		})
+	})
+
+	Describe("json translation", func() {
+		It("translates nested property", func() {
+			query, err := trs.Translate(`jsonField.nestedProperty1.stringProperty == "abcd"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(json_extract(jsonField, '$.nestedProperty1.stringProperty') = 'abcd')")))
+		})
+
+		It("translates other primitive types", func() {
+			query, err := trs.Translate("jsonField.intProperty <= 2 and jsonField.floatProperty > 1.5 or jsonField.boolProperty")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((cast(json_extract(jsonField, '$.intProperty') as integer) <= 2) and (cast(json_extract(jsonField, '$.floatProperty') as real) > 1.5)) or json_extract(jsonField, '$.boolProperty'))")))
+		})
+
+		It("translates timestamp", func() {
+			query, err := trs.Translate(`jsonField.tsProperty >= "2024-09-17T08:00:00Z"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(julianday(json_extract(jsonField, '$.tsProperty'), 'auto') >= julianday('2024-09-17T08:00:00Z', 'auto'))")))
+		})
+
+		It("translates epoch timestamps", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "event", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+					"seenAt": filter.JSONTimestamp(filter.JSONTimestampFormatEpochMillis),
+				}},
+			}, filter.TranslatorDialectSQLite)
+
+			query, err := trs.Translate(`event.seenAt >= "2024-09-17T08:00:00Z"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(julianday(datetime(cast(json_extract(event, '$.seenAt') as real) / 1000.0, 'unixepoch', 'subsec'), 'auto') >= julianday('2024-09-17T08:00:00Z', 'auto'))")))
+		})
+	})
+
+	Describe("expressions", func() {
+		It("translates string expressions", func() {
+			query, err := trs.Translate(`(stringField startsWith "a*b" or stringField endsWith "[x]?") and (stringField matches "[A-Z]+" or stringField contains "ijkl")`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((stringField GLOB 'a[*]b*') or (stringField GLOB '*[[]x][?]')) and ((stringField REGEXP '[A-Z]+') or (stringField GLOB '*ijkl*')))")))
+		})
+
+		It("translates comparison of timestamp identifiers", func() {
+			query, err := trs.Translate(`tsField < jsonField.tsProperty`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(julianday(ts_field, 'auto') < julianday(json_extract(jsonField, '$.tsProperty'), 'auto'))")))
+		})
+
+		It("translates functions", func() {
+			query, err := trs.Translate(`round(floatField) > 3.0 and tsField < now() and date(jsonField.nestedProperty1.stringProperty) > date("2025-01-01")`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((round(floatField) > 3) and (julianday(ts_field, 'auto') < julianday(CURRENT_TIMESTAMP, 'auto'))) and (julianday(json_extract(jsonField, '$.nestedProperty1.stringProperty'), 'auto') > julianday('2025-01-01T00:00:00Z', 'auto')))")))
+		})
+
+		It("truncates timestamps to the day", func() {
+			query, err := trs.Translate(`date(tsField) == date("2025-01-01")`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(julianday(date(ts_field, 'auto'), 'auto') = julianday('2025-01-01T00:00:00Z', 'auto'))")))
+		})
+
+		It("keeps rounded integers as integers", func() {
+			query, err := trs.Translate(`round(intField) / 2 == 1`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((cast(round(intField) as integer) / 2) = 1)")))
+		})
+
+		It("translates timestamp and duration arithmetic", func() {
+			query, err := trs.Translate(`tsField > now() - duration("24h") and tsField - jsonField.tsProperty < duration("1ms") and duration("1s") + tsField < now()`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((julianday(ts_field, 'auto') > julianday((julianday(CURRENT_TIMESTAMP, 'auto') - 86400 / 86400.0), 'auto')) and (((julianday(ts_field, 'auto') - julianday(json_extract(jsonField, '$.tsProperty'), 'auto')) * 86400.0) < 0.001)) and (julianday((1 / 86400.0 + julianday(ts_field, 'auto')), 'auto') < julianday(CURRENT_TIMESTAMP, 'auto')))")))
+		})
+
+		It("translates date part accessors", func() {
+			query, err := trs.Translate(`tsField.YearDay() > 100 and tsField.Minute() == 0`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((cast(strftime('%j', ts_field, 'auto') as integer) > 100) and (cast(strftime('%M', ts_field, 'auto') as integer) = 0))")))
+		})
+
+		It("translates array expressions", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "tags", Type: filter.IdentifierTypeArray(filter.IdentifierTypeString)},
+				{ExprName: "scores", Type: filter.IdentifierTypeArray(filter.IdentifierTypeInt)},
+			}, filter.TranslatorDialectSQLite)
+
+			query, err := trs.Translate(`"vip" in tags and any(scores, # > 50) and len(filter(tags, # != "x")) > 1`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("((('vip' IN (SELECT value FROM json_each(tags))) and (EXISTS (SELECT 1 FROM json_each(scores) AS elem1 WHERE (elem1.value > 50)))) and (json_array_length((SELECT json_group_array(elem1.value) FROM json_each(tags) AS elem1 WHERE (elem1.value <> 'x'))) > 1))")))
+		})
+
+		It("translates json array expressions", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "order", DBName: "order_data", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+					"items": filter.JSONArray(filter.JSONTree{"qty": filter.JSONLeaf(filter.IdentifierTypeInt)}),
+					"tags":  filter.JSONArray(filter.JSONLeaf(filter.IdentifierTypeString)),
+				}},
+			}, filter.TranslatorDialectSQLite)
+
+			query, err := trs.Translate(`order.items[0].qty > 1 and any(order.items, .qty > 3) and "gift" in order.tags`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((cast(json_extract(order_data, '$.items[0].qty') as integer) > 1) and (EXISTS (SELECT 1 FROM json_each(json_extract(order_data, '$.items')) AS elem1 WHERE (cast(json_extract(elem1.value, '$.qty') as integer) > 3)))) and ('gift' IN (SELECT value FROM json_each(json_extract(order_data, '$.tags')))))")))
+		})
+
+		It("translates dynamic json expressions", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "attrs", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONDynamic(nil)},
+			}, filter.TranslatorDialectSQLite)
+
+			query, err := trs.Translate(`attrs.size > 3 and attrs["first name"] == "x" and bool(attrs.dims.flat)`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((cast(json_extract(attrs, '$.size') as real) > 3) and (json_extract(attrs, '$.\"first name\"') = 'x')) and json_extract(attrs, '$.dims.flat'))")))
+		})
+
+		It("translates case-insensitive expressions", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "name", Type: filter.IdentifierTypeString, CaseInsensitive: true},
+			}, filter.TranslatorDialectSQLite)
+
+			query, err := trs.Translate(`name startsWith "a_c" or name == "ACME"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`((name LIKE 'a\_c%' ESCAPE '\') or (lower(name) = lower('ACME')))`)))
+		})
+
+		It("compares timestamps as julian days with case-insensitive strings", func() {
+			trs = newTranslator([]filter.Identifier{
+				{ExprName: "tsField", Type: filter.IdentifierTypeTimestamp},
+				{ExprName: "jsonField", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+					"tsProperty": filter.JSONLeaf(filter.IdentifierTypeTimestamp),
+				}},
+			}, filter.TranslatorDialectSQLite, filter.WithCaseInsensitiveStrings())
+
+			query, err := trs.Translate(`tsField == "2024-01-01T00:00:00Z" and jsonField.tsProperty != tsField`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition(`((julianday(tsField, 'auto') = julianday('2024-01-01T00:00:00Z', 'auto')) and (julianday(json_extract(jsonField, '$.tsProperty'), 'auto') <> julianday(tsField, 'auto')))`)))
+		})
+
+		It("fails for conditional string patterns", func() {
+			for _, query := range []string{
+				`stringField contains (intField > 0 ? "a" : "b")`,
+				`stringField startsWith (true ? "a" : "b")`,
+				`stringField contains ("a" ?? "b")`,
+			} {
+				_, err := trs.Translate(query)
+
+				Expect(err).To(HaveOccurred())
+				Expect(filter.IsUnsupportedOperation(err)).To(BeTrue())
+			}
+		})
+
+		It("translates order by", func() {
+			orderBy, err := trs.TranslateOrderBy("tsField desc nulls last, intField")
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(orderBy).To(Equal(filter.SQLOrderBy("ts_field DESC NULLS LAST, intField ASC")))
+		})
+
+		It("translates exponent", func() {
+			query, err := trs.Translate(`floatField > floatField ** 2`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(floatField > (pow(floatField, 2)))")))
+		})
+
+		It("binds parameters", func() {
+			query, args, err := trs.TranslateParams(`intField > 2 and tsField <= "2024-09-17T08:00:00Z" and stringField contains "a?"`)
+
+			Expect(err).ToNot(HaveOccurred())
+			Expect(query).To(Equal(filter.SQLWhereCondition("(((intField > ?) and (julianday(ts_field, 'auto') <= julianday(?, 'auto'))) and (stringField GLOB ?))")))
+			Expect(args).To(Equal([]any{2, time.Date(2024, 9, 17, 8, 0, 0, 0, time.UTC), "*a[?]*"}))
+		})
+	})
+})
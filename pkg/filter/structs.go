@@ -0,0 +1,267 @@
+package filter
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+type StructOption func(o *structOptions)
+
+type structOptions struct {
+	exprTag         string
+	dbTag           string
+	skipUnsupported bool
+}
+
+// WithExprTag sets the struct tag holding the expr name of the field, "expr" by default
+func WithExprTag(tag string) StructOption {
+	return func(o *structOptions) {
+		o.exprTag = tag
+	}
+}
+
+// WithDBTag sets the struct tag holding the column name of the field, "db" by default
+func WithDBTag(tag string) StructOption {
+	return func(o *structOptions) {
+		o.dbTag = tag
+	}
+}
+
+// WithUnsupportedFieldsSkipped skips fields and JSON properties of unsupported types, like []byte or any,
+// instead of failing
+func WithUnsupportedFieldsSkipped() StructOption {
+	return func(o *structOptions) {
+		o.skipUnsupported = true
+	}
+}
+
+var (
+	timeType       = reflect.TypeFor[time.Time]()
+	rawMessageType = reflect.TypeFor[json.RawMessage]()
+	valuerType     = reflect.TypeFor[driver.Valuer]()
+)
+
+// IdentifiersFromStruct builds the allowed identifiers from exported fields of the struct (or a pointer to it),
+// named by `expr:"name" db:"column_name"` tags or by the field name if untagged. Fields tagged with "-" are skipped,
+// untagged embedded structs are flattened, slices of scalars become array identifiers, nullable types like
+// sql.NullString become identifiers of the wrapped type, and nested structs, as well as slices of them, become
+// JSON identifiers whose properties are named by json tags, with slices as JSON arrays.
+func IdentifiersFromStruct(v any, opts ...StructOption) ([]Identifier, error) {
+	o := structOptions{exprTag: "expr", dbTag: "db"}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	t := reflect.TypeOf(v)
+	if t == nil || indirect(t).Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected struct, instead found %v", t)
+	}
+	t = indirect(t)
+	return o.identifiers(t)
+}
+
+func (o structOptions) identifiers(t reflect.Type) ([]Identifier, error) {
+	var identifiers []Identifier
+	for _, field := range visibleFields(t, o.exprTag) {
+		exprName, ok := tagName(field, o.exprTag)
+		if !ok {
+			continue
+		}
+		dbName, _, _ := strings.Cut(field.Tag.Get(o.dbTag), ",") // column defaults to the expr name
+		if dbName == "-" {
+			dbName = ""
+		}
+		fieldType := indirect(field.Type)
+		if valueType, ok := nullableValue(fieldType); ok {
+			fieldType = valueType
+		}
+		identifierType, jsonSpec, err := o.column(fieldType, []reflect.Type{t})
+		if err != nil && o.skipUnsupported {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field %v: %w", field.Name, err)
+		}
+		identifiers = append(identifiers, Identifier{
+			ExprName: exprName,
+			DBName:   dbName,
+			Type:     identifierType,
+			JSONSpec: jsonSpec,
+		})
+	}
+	return identifiers, nil
+}
+
+// visibleFields returns the fields of the struct along with those promoted from embedded structs, which are only
+// promoted when the embedded struct is not named by the tag, like by encoding/json
+func visibleFields(t reflect.Type, tag string) []reflect.StructField {
+	return slices.DeleteFunc(reflect.VisibleFields(t), func(field reflect.StructField) bool {
+		for i := 1; i < len(field.Index); i++ {
+			if !promoting(t.FieldByIndex(field.Index[:i]), tag) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// promoting reports whether the fields of the embedded struct are promoted, as it is not named by the tag
+func promoting(field reflect.StructField, tag string) bool {
+	name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+	return field.Anonymous && indirect(field.Type).Kind() == reflect.Struct && name == ""
+}
+
+// tagName returns the name of the field from the tag, or the field name if untagged.
+// Unexported, skipped and promoting embedded struct fields are not named, except for embedded structs
+// of unexported types named by json tags, which encoding/json marshals too.
+func tagName(field reflect.StructField, tag string) (string, bool) {
+	embedded := field.Anonymous && indirect(field.Type).Kind() == reflect.Struct
+	if !field.IsExported() && !(embedded && tag == "json") || promoting(field, tag) {
+		return "", false
+	}
+	name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		return field.Name, true
+	default:
+		return name, true
+	}
+}
+
+// nullableValue returns the type of the value wrapped by nullable types like sql.NullString or sql.Null[T],
+// which are structs of the value and its Valid flag, implementing driver.Valuer to store NULL unless valid
+func nullableValue(t reflect.Type) (reflect.Type, bool) {
+	if t.Kind() != reflect.Struct || t.NumField() != 2 || !reflect.PointerTo(t).Implements(valuerType) {
+		return nil, false
+	}
+	valid, ok := t.FieldByName("Valid")
+	if !ok || valid.Type.Kind() != reflect.Bool {
+		return nil, false
+	}
+	return t.Field(1 - valid.Index[0]).Type, true
+}
+
+// column maps the Go type of a field to its identifier type: slices of scalars to arrays, slices of JSON objects
+// to JSON arrays, and other types like JSON elements
+func (o structOptions) column(t reflect.Type, parents []reflect.Type) (IdentifierType, JSONElement, error) {
+	if identifierType, ok := o.arrayType(t); ok {
+		return identifierType, nil, nil
+	}
+	if isArray(t) {
+		if elemType, _, err := o.jsonElement(t.Elem(), parents); err == nil && elemType == IdentifierTypeJSON {
+			spec, err := o.jsonSpec(t, parents)
+			return IdentifierTypeJSON, spec, err
+		}
+	}
+	return o.jsonElement(t, parents)
+}
+
+// arrayType maps slices of scalars to array identifier types
+func (o structOptions) arrayType(t reflect.Type) (IdentifierType, bool) {
+	t = indirect(t)
+	if !isArray(t) {
+		return "", false
+	}
+	elemType, _, err := o.jsonElement(t.Elem(), nil)
+	if err != nil || elemType == IdentifierTypeJSON {
+		return "", false
+	}
+	return IdentifierTypeArray(elemType), true
+}
+
+// jsonElement maps the Go type to its identifier type, with the spec of nested JSON properties.
+// Parents are the struct types containing the type, used to reject recursive types.
+func (o structOptions) jsonElement(t reflect.Type, parents []reflect.Type) (IdentifierType, JSONElement, error) {
+	t = indirect(t)
+	switch {
+	case t == timeType:
+		return IdentifierTypeTimestamp, nil, nil
+	case t == rawMessageType:
+		return IdentifierTypeJSON, JSONTree{}, nil
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return IdentifierTypeInt, nil, nil
+	case reflect.Float32, reflect.Float64:
+		return IdentifierTypeFloat, nil, nil
+	case reflect.Bool:
+		return IdentifierTypeBool, nil, nil
+	case reflect.String:
+		return IdentifierTypeString, nil, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return "", nil, fmt.Errorf("unsupported map key type %v", t.Key())
+		}
+		return IdentifierTypeJSON, JSONDynamic(nil), nil // keys are not known in advance
+	case reflect.Struct:
+		if slices.Contains(parents, t) {
+			return "", nil, fmt.Errorf("recursive type %v", t)
+		}
+		tree, err := o.jsonTree(t, append(slices.Clone(parents), t))
+		if err != nil {
+			return "", nil, err
+		}
+		return IdentifierTypeJSON, tree, nil
+	default:
+		return "", nil, fmt.Errorf("unsupported type %v", t)
+	}
+}
+
+// jsonTree describes the properties of the struct as they are marshalled by encoding/json
+func (o structOptions) jsonTree(t reflect.Type, parents []reflect.Type) (JSONTree, error) {
+	tree := JSONTree{}
+	for _, field := range visibleFields(t, "json") {
+		name, ok := tagName(field, "json")
+		if !ok {
+			continue
+		}
+		element, err := o.jsonSpec(field.Type, parents)
+		if err != nil && o.skipUnsupported {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v.%v: %w", t.Name(), field.Name, err)
+		}
+		tree[name] = element
+	}
+	return tree, nil
+}
+
+// jsonSpec describes the JSON element the Go type is marshalled to, where slices are JSON arrays
+func (o structOptions) jsonSpec(t reflect.Type, parents []reflect.Type) (JSONElement, error) {
+	t = indirect(t)
+	if isArray(t) {
+		element, err := o.jsonSpec(t.Elem(), parents)
+		if err != nil {
+			return nil, err
+		}
+		return JSONArray(element), nil
+	}
+	identifierType, element, err := o.jsonElement(t, parents)
+	if err != nil {
+		return nil, err
+	}
+	if identifierType == IdentifierTypeJSON {
+		return element, nil
+	}
+	return JSONLeaf(identifierType), nil
+}
+
+// isArray reports whether the type is a slice or array other than bytes, which are encoded as strings
+func isArray(t reflect.Type) bool {
+	return t != rawMessageType && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,227 @@
+package filter_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/happening-oss/expr2sql/pkg/filter"
+)
+
+type auditFields struct {
+	CreatedAt time.Time  `expr:"createdAt" db:"created_at"`
+	DeletedAt *time.Time `expr:"deletedAt" db:"deleted_at"`
+}
+
+type address struct {
+	City    string `json:"city"`
+	ZipCode int    `json:"zip_code"`
+	Geo     struct {
+		Lat float64 `json:"lat"`
+		Lng float64 `json:"lng"`
+	} `json:"geo"`
+	Phones []string `json:"phones"`
+	Note   string   `json:"-"`
+}
+
+type customer struct {
+	auditFields
+
+	ID       int64             `expr:"id"`
+	Name     string            `expr:"name" db:"full_name"`
+	Balance  float32           `expr:"balance"`
+	Active   bool              `expr:"active"`
+	Address  *address          `expr:"address"`
+	Settings json.RawMessage   `expr:"settings"`
+	Labels   map[string]string `expr:"labels"`
+	Tags     []string          `expr:"tags"`
+	Internal string            `expr:"-" db:"internal"`
+	Untagged string
+
+	secret string
+}
+
+var _ = Describe("IdentifiersFromStruct", func() {
+	It("builds identifiers from tags", func() {
+		identifiers, err := filter.IdentifiersFromStruct(&customer{})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(identifiers).To(Equal([]filter.Identifier{
+			{ExprName: "createdAt", DBName: "created_at", Type: filter.IdentifierTypeTimestamp},
+			{ExprName: "deletedAt", DBName: "deleted_at", Type: filter.IdentifierTypeTimestamp},
+			{ExprName: "id", Type: filter.IdentifierTypeInt},
+			{ExprName: "name", DBName: "full_name", Type: filter.IdentifierTypeString},
+			{ExprName: "balance", Type: filter.IdentifierTypeFloat},
+			{ExprName: "active", Type: filter.IdentifierTypeBool},
+			{ExprName: "address", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+				"city":     filter.JSONLeaf(filter.IdentifierTypeString),
+				"zip_code": filter.JSONLeaf(filter.IdentifierTypeInt),
+				"geo": filter.JSONTree{
+					"lat": filter.JSONLeaf(filter.IdentifierTypeFloat),
+					"lng": filter.JSONLeaf(filter.IdentifierTypeFloat),
+				},
+				"phones": filter.JSONArray(filter.JSONLeaf(filter.IdentifierTypeString)),
+			}},
+			{ExprName: "settings", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{}},
+			{ExprName: "labels", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONDynamic(nil)},
+			{ExprName: "tags", Type: filter.IdentifierTypeArray(filter.IdentifierTypeString)},
+			{ExprName: "Untagged", Type: filter.IdentifierTypeString},
+		}))
+	})
+
+	It("uses custom tags", func() {
+		identifiers, err := filter.IdentifiersFromStruct(struct {
+			Name string `filter:"name" column:"full_name" db:"ignored"`
+		}{}, filter.WithExprTag("filter"), filter.WithDBTag("column"))
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(identifiers).To(Equal([]filter.Identifier{
+			{ExprName: "name", DBName: "full_name", Type: filter.IdentifierTypeString},
+		}))
+	})
+
+	It("translates with built identifiers", func() {
+		identifiers, err := filter.IdentifiersFromStruct(customer{})
+		Expect(err).ToNot(HaveOccurred())
+
+		query, err := newTranslator(identifiers, filter.TranslatorDialectPostgres).
+			Translate(`name == "abcd" and address.geo.lat > 45.5 and deletedAt == nil`)
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(query).To(Equal(filter.SQLWhereCondition("(((full_name = 'abcd') and (cast(address -> 'geo' ->> 'lat' as float) > 45.5)) and (deleted_at IS NULL))")))
+	})
+
+	It("maps nullable types to the wrapped type", func() {
+		identifiers, err := filter.IdentifiersFromStruct(struct {
+			Nickname   sql.NullString    `expr:"nickname"`
+			Age        *sql.NullInt64    `expr:"age"`
+			Score      sql.Null[float64] `expr:"score"`
+			VerifiedAt sql.NullTime      `expr:"verifiedAt"`
+		}{})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(identifiers).To(Equal([]filter.Identifier{
+			{ExprName: "nickname", Type: filter.IdentifierTypeString},
+			{ExprName: "age", Type: filter.IdentifierTypeInt},
+			{ExprName: "score", Type: filter.IdentifierTypeFloat},
+			{ExprName: "verifiedAt", Type: filter.IdentifierTypeTimestamp},
+		}))
+	})
+
+	It("evaluates nullable types", func() {
+		type profile struct {
+			Nickname sql.NullString `expr:"nickname"`
+			Age      sql.NullInt64  `expr:"age"`
+		}
+		identifiers, err := filter.IdentifiersFromStruct(profile{})
+		Expect(err).ToNot(HaveOccurred())
+
+		matches, err := filter.NewEvaluator(identifiers).Evaluate(`nickname == "abcd" and age == nil`,
+			profile{Nickname: sql.NullString{String: "abcd", Valid: true}})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(matches).To(BeTrue())
+	})
+
+	It("skips unsupported fields with the option", func() {
+		identifiers, err := filter.IdentifiersFromStruct(struct {
+			ID      [16]byte `expr:"id"`
+			Payload []byte   `expr:"payload"`
+			Extra   any      `expr:"extra"`
+			Name    string   `expr:"name"`
+			Meta    struct {
+				Raw  []byte `json:"raw"`
+				Kind string `json:"kind"`
+			} `expr:"meta"`
+		}{}, filter.WithUnsupportedFieldsSkipped())
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(identifiers).To(Equal([]filter.Identifier{
+			{ExprName: "name", Type: filter.IdentifierTypeString},
+			{ExprName: "meta", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+				"kind": filter.JSONLeaf(filter.IdentifierTypeString),
+			}},
+		}))
+	})
+
+	It("maps slices of structs and maps to JSON arrays", func() {
+		identifiers, err := filter.IdentifiersFromStruct(struct {
+			Items []struct {
+				SKU string `json:"sku"`
+			} `expr:"items"`
+			Attrs []map[string]any `expr:"attrs"`
+		}{})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(identifiers).To(Equal([]filter.Identifier{
+			{ExprName: "items", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONArray(filter.JSONTree{
+				"sku": filter.JSONLeaf(filter.IdentifierTypeString),
+			})},
+			{ExprName: "attrs", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONArray(filter.JSONDynamic(nil))},
+		}))
+	})
+
+	It("names tagged embedded structs instead of flattening them", func() {
+		type Audit = auditFields
+		type geo struct {
+			Lat float64 `json:"lat"`
+		}
+		type location struct {
+			geo `json:"position"`
+			*address
+		}
+		identifiers, err := filter.IdentifiersFromStruct(struct {
+			Audit    `expr:"audit"`
+			Location location `expr:"location"`
+		}{})
+
+		Expect(err).ToNot(HaveOccurred())
+		Expect(identifiers).To(Equal([]filter.Identifier{
+			{ExprName: "audit", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+				"CreatedAt": filter.JSONLeaf(filter.IdentifierTypeTimestamp),
+				"DeletedAt": filter.JSONLeaf(filter.IdentifierTypeTimestamp),
+			}},
+			{ExprName: "location", Type: filter.IdentifierTypeJSON, JSONSpec: filter.JSONTree{
+				"position": filter.JSONTree{
+					"lat": filter.JSONLeaf(filter.IdentifierTypeFloat),
+				},
+				"geo": filter.JSONTree{
+					"lat": filter.JSONLeaf(filter.IdentifierTypeFloat),
+					"lng": filter.JSONLeaf(filter.IdentifierTypeFloat),
+				},
+				"city":     filter.JSONLeaf(filter.IdentifierTypeString),
+				"zip_code": filter.JSONLeaf(filter.IdentifierTypeInt),
+				"phones":   filter.JSONArray(filter.JSONLeaf(filter.IdentifierTypeString)),
+			}},
+		}))
+	})
+
+	It("fails for unsupported field types", func() {
+		_, err := filter.IdentifiersFromStruct(struct {
+			Matrix [][]int `expr:"matrix"`
+		}{})
+
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails for recursive types", func() {
+		type node struct {
+			Value int   `json:"value"`
+			Next  *node `json:"next"`
+		}
+		_, err := filter.IdentifiersFromStruct(struct {
+			List node `expr:"list"`
+		}{})
+
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("fails for non-struct values", func() {
+		_, err := filter.IdentifiersFromStruct(42)
+
+		Expect(err).To(HaveOccurred())
+	})
+})
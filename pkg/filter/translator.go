@@ -1,17 +1,1067 @@
 package filter
 
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/expr-lang/expr/ast"
+	"github.com/expr-lang/expr/parser"
+
+	"github.com/happening-oss/expr2sql/pkg/filter/internal"
+)
+
 type TranslatorDialect byte
 
 const (
 	TranslatorDialectPostgres TranslatorDialect = iota + 1
+	TranslatorDialectMySQL
+	TranslatorDialectSQLite
 )
 
-func NewTranslator(allowedIdentifiers []Identifier, _ TranslatorDialect) Translator {
-	return newPostgresTranslator(allowedIdentifiers)
+// NewTranslator creates a translator of queries referencing the allowed identifiers. Translations referencing
+// identifiers fail with ErrInvalidIdentifier if any database name is not a plain, optionally qualified, column name,
+// see NewValidatedTranslator to fail upfront.
+func NewTranslator(allowedIdentifiers []Identifier, dialect TranslatorDialect, opts ...TranslatorOption) Translator {
+	var t *translator
+	switch dialect {
+	case TranslatorDialectMySQL:
+		t = newTranslator(allowedIdentifiers, mysqlDialect{})
+	case TranslatorDialectSQLite:
+		t = newTranslator(allowedIdentifiers, sqliteDialect{})
+	default:
+		t = newTranslator(allowedIdentifiers, postgresDialect{})
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	for _, identifier := range allowedIdentifiers {
+		if err := identifier.validate(); err != nil {
+			t.invalidIdentifier = err
+			break
+		}
+	}
+	return t
+}
+
+// NewValidatedTranslator creates a translator like NewTranslator, failing with ErrInvalidIdentifier
+// for database names which are not plain, optionally qualified, column names
+func NewValidatedTranslator(allowedIdentifiers []Identifier, dialect TranslatorDialect, opts ...TranslatorOption) (Translator, error) {
+	t := NewTranslator(allowedIdentifiers, dialect, opts...)
+	if err := t.(*translator).invalidIdentifier; err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+type TranslatorOption func(t *translator)
+
+// WithCaseInsensitiveStrings makes string operators ignore case of all string identifiers and JSON string properties
+func WithCaseInsensitiveStrings() TranslatorOption {
+	return func(t *translator) {
+		t.caseInsensitive = true
+	}
+}
+
+// WithClock makes now() the time returned by the clock, translated as a timestamp literal,
+// instead of the current timestamp of the database, e.g. for reproducible queries in tests
+func WithClock(clock func() time.Time) TranslatorOption {
+	return func(t *translator) {
+		t.clock = clock
+	}
+}
+
+// WithJSONContainment translates comparisons of JSON properties with literals to conditions on the whole JSON column,
+// which can use its GIN index: equality to the jsonb containment operator @>, and other comparisons to JSONPath filters
+// of the @? operator. Comparisons are only translated where missing properties don't match either way, i.e. not for !=
+// or within negations, which keep the comparisons of the properties.
+// Only supported by Postgres, other dialects ignore the option.
+func WithJSONContainment() TranslatorOption {
+	return func(t *translator) {
+		t.jsonContainment = true
+	}
+}
+
+// WithCaseSensitiveNames quotes database names containing upper case letters, which Postgres would otherwise
+// fold to lower case. Only supported by Postgres, other dialects ignore the option.
+func WithCaseSensitiveNames() TranslatorOption {
+	return func(t *translator) {
+		if _, ok := t.dialect.(postgresDialect); ok {
+			t.dialect = postgresDialect{caseSensitiveNames: true}
+		}
+	}
+}
+
+// WithSimplifiedSQL simplifies queries before their translation, so that the SQL is readable, e.g. in query logs:
+// operations on constants are folded, redundant boolean terms and double negations are removed, and parentheses
+// are only emitted where the precedence of operators demands. Queries are validated as written,
+// so they fail with the same errors as without the option.
+func WithSimplifiedSQL() TranslatorOption {
+	return func(t *translator) {
+		t.simplified = true
+	}
 }
 
 type SQLWhereCondition string
 
 type Translator interface {
+	// Translate translates the query to a condition with all literals inlined
 	Translate(query string) (SQLWhereCondition, error)
+	// TranslateParams translates the query to a condition with bind placeholders for all literals,
+	// returning their values in placeholder order
+	TranslateParams(query string) (SQLWhereCondition, []any, error)
+	// TranslateHaving translates the query over the aggregates of the rows grouped by the comma-separated grouping keys,
+	// as parsed by TranslateGroupBy, to a HAVING condition with all literals inlined. Identifiers outside of aggregates
+	// need to be grouping keys, while all rows are a single group if there are none.
+	TranslateHaving(query string, groupBy string) (SQLHavingCondition, error)
+	// TranslateHavingParams translates the HAVING condition like TranslateHaving, with bind placeholders for all literals,
+	// returning their values in placeholder order
+	TranslateHavingParams(query string, groupBy string) (SQLHavingCondition, []any, error)
+	// TranslateGroupBy translates comma-separated grouping keys, each an identifier or a JSON property of a scalar type,
+	// to the items of the GROUP BY clause
+	TranslateGroupBy(groupBy string) (SQLGroupBy, error)
+	// TranslateOrderBy translates comma-separated sort keys, as parsed by ParseSort, to the items of the ORDER BY clause
+	TranslateOrderBy(sort string) (SQLOrderBy, error)
+}
+
+// dialect encapsulates SQL syntax which differs between databases
+type dialect interface {
+	// operatorOverrides returns operators which replace the common binaryOperators
+	operatorOverrides() map[string]internal.BinaryOperatorDescriptor
+	// caseInsensitiveOperators returns operators which replace the common caseInsensitiveBinaryOperators
+	caseInsensitiveOperators() map[string]internal.BinaryOperatorDescriptor
+	// functionOverrides returns functions which replace or extend the common builtinFunctions
+	functionOverrides() map[string]internal.FunctionDescriptor
+	// dateParts returns the accessors of timestamp parts, named after the methods of time.Time, e.g. Year
+	dateParts() map[string]internal.FunctionDescriptor
+	// identifier formats a name within the qualified column name, quoting it when needed
+	identifier(name string) string
+	// jsonExpr formats access to the element at the path within the JSON column,
+	// where the path consists of string keys of objects and int indices of arrays
+	jsonExpr(column string, path []any, exprType internal.ExprType) string
+	// jsonTimestamp converts the JSON value, extracted as a string or a number depending on the format, to a timestamp
+	jsonTimestamp(value string, format JSONTimestampFormat) string
+	// jsonCondition formats the comparison of the element at the path within the JSON column with the value
+	// as a condition on the column, which can use its GIN index, reporting false if the dialect has no such index
+	// and translates such comparisons like any other
+	jsonCondition(column internal.TranslationResult, path []any, op string, value any) (internal.TranslationResult, bool)
+	// arrayElements formats the table of the array elements named by the alias, along with the element expression
+	arrayElements(array string, elemType internal.ExprType, alias string) (table string, elem string)
+	// arrayAggregate formats the aggregation of the element expression to an array, which is empty for no rows
+	arrayAggregate(elem string) string
+	// jsonArrayElements formats the table of the JSON array elements named by the alias, along with the element
+	// expression, which is a JSON value for elements of other than scalar types
+	jsonArrayElements(array string, elemType internal.ExprType, alias string) (table string, elem string)
+	// jsonArrayAggregate formats the aggregation of the element expression to a JSON array, which is empty for no rows
+	jsonArrayAggregate(elem string) string
+	// orderBy formats the template of the ORDER BY item sorting by the key referenced as {0}
+	orderBy(desc bool, nulls SortNulls) string
+	// quote formats a string literal which is part of the generated SQL, e.g. a JSON key, rather than a bind argument
+	quote(s string) string
+	// literal formats a bind argument as an inline SQL literal
+	literal(value any) string
+	// placeholder formats the bind placeholder for the argument at the index
+	placeholder(index int) string
+}
+
+var unaryOperators = map[string]internal.UnaryOperatorDescriptor{
+	"!":   internal.UnaryBooleanOperatorDescriptor("not"),
+	"not": internal.UnaryBooleanOperatorDescriptor("not"),
+	"-":   internal.UnaryNumericOperatorDescriptor("-"),
+}
+
+var binaryOperators = map[string]internal.BinaryOperatorDescriptor{
+	"+": internal.NumericOperatorDescriptor("+"),
+	"-": internal.NumericOperatorDescriptor("-"),
+	"*": internal.NumericOperatorDescriptor("*"),
+	"/": internal.NumericOperatorDescriptor("/"),
+	"%": internal.NumericOperatorDescriptor("%"),
+
+	"==": internal.NillableComparisonOperatorDescriptor("=", "IS"),
+	"!=": internal.NillableComparisonOperatorDescriptor("<>", "IS NOT"),
+	"<":  internal.ComparisonOperatorDescriptor("<"),
+	">":  internal.ComparisonOperatorDescriptor(">"),
+	"<=": internal.ComparisonOperatorDescriptor("<="),
+	">=": internal.ComparisonOperatorDescriptor(">="),
+
+	"in":     internal.MembershipOperatorDescriptor("IN", false),
+	"not in": internal.MembershipOperatorDescriptor("NOT IN", true), // parsed as negation of "in"
+
+	"&&":  internal.BooleanOperatorDescriptor("and"),
+	"and": internal.BooleanOperatorDescriptor("and"),
+	"||":  internal.BooleanOperatorDescriptor("or"),
+	"or":  internal.BooleanOperatorDescriptor("or"),
+
+	"contains":   internal.StringLikeOperatorDescriptor("%", "%"),
+	"startsWith": internal.StringLikeOperatorDescriptor("", "%"),
+	"endsWith":   internal.StringLikeOperatorDescriptor("%", ""),
+}
+
+// caseInsensitiveBinaryOperators replace binaryOperators for case-insensitive operands
+var caseInsensitiveBinaryOperators = map[string]internal.BinaryOperatorDescriptor{
+	"==": internal.CaseInsensitiveOperatorDescriptor(internal.NillableComparisonOperatorDescriptor("=", "IS")),
+	"!=": internal.CaseInsensitiveOperatorDescriptor(internal.NillableComparisonOperatorDescriptor("<>", "IS NOT")),
+}
+
+var builtinFunctions = map[string]internal.FunctionDescriptor{
+	"len":   internal.LengthFunctionDescriptor("length({0})"),
+	"lower": internal.StringFunctionDescriptor("lower({0})"),
+	"upper": internal.StringFunctionDescriptor("upper({0})"),
+	"trim":  internal.StringFunctionDescriptor("trim({0})"),
+	"abs":   internal.NumericFunctionDescriptor("abs({0})"),
+	"ceil":  internal.NumericFunctionDescriptor("ceil({0})"),
+	"floor": internal.NumericFunctionDescriptor("floor({0})"),
+	"round": internal.NumericFunctionDescriptor("round({0})"),
+	"now":   internal.TemplateFunctionDescriptor("CURRENT_TIMESTAMP", nil, internal.ExprTypeTimestampIdentifier),
+}
+
+type translator struct {
+	allowedIdentifiers []Identifier
+	dialect            dialect
+	caseInsensitive    bool
+	functions          map[string]Function
+	clock              func() time.Time
+	jsonContainment    bool
+	simplified         bool
+	invalidIdentifier  error // fails translations referencing identifiers, as the database names are not plain names
+
+	element     *internal.TranslationResult // array element referenced as # within a predicate
+	elementSpec JSONElement                 // spec of the element of JSON arrays
+	depth       int                         // number of predicates enclosing the translated node
+	relatedRows bool                        // the translated node is within a predicate over related rows
+
+	dynamicType  internal.ExprType              // type of dynamic JSON values, given by a cast or inferred from the other operand
+	dynamicTypes map[ast.Node]internal.ExprType // records the types of dynamic JSON values for the Evaluator
+
+	unified map[ast.Node]internal.TranslationResult // records the unified results of conditional and nil-coalescing expressions for the Evaluator
+	filters map[ast.Node]bool                       // conditions filtering rows alike whether false or NULL, as JSON conditions may differ in NULL
+
+	having       bool     // aggregates can be called, as the query is a HAVING condition
+	groupingKeys []string // translated grouping keys, which the HAVING condition can reference outside of aggregates
+	aggregated   bool     // the translated node is an argument of an aggregate
+}
+
+func newTranslator(allowedIdentifiers []Identifier, dialect dialect) *translator {
+	return &translator{allowedIdentifiers: allowedIdentifiers, dialect: dialect}
+}
+
+func (t *translator) Translate(query string) (SQLWhereCondition, error) {
+	result, err := t.translateCondition(query)
+	if err != nil {
+		return "", err
+	}
+	return SQLWhereCondition(t.inline(result)), nil
+}
+
+func (t *translator) TranslateParams(query string) (SQLWhereCondition, []any, error) {
+	result, err := t.translateCondition(query)
+	if err != nil {
+		return "", nil, err
+	}
+	return SQLWhereCondition(internal.BindArgs(result.Expr, t.dialect.placeholder)), result.Args, nil
+}
+
+func (t *translator) translateCondition(query string) (internal.TranslationResult, error) {
+	_, result, err := t.compile(query)
+	return result, err
+}
+
+// compile parses and translates the query, returning its syntax tree along with the translation
+func (t *translator) compile(query string) (*parser.Tree, internal.TranslationResult, error) {
+	parsed, err := t.parse(query)
+	if err != nil {
+		return nil, internal.TranslationResult{}, bindSource(parsingError(err, query), query)
+	}
+	result, err := t.translateFilter(parsed.Node)
+	if err != nil {
+		return nil, internal.TranslationResult{}, bindSource(err, query)
+	}
+	if result.Type != internal.ExprTypeBool && result.Type != internal.ExprTypeBoolIdentifier {
+		err := newTranslationError(ErrorCodeInvalidFilter, parsed.Node, ErrInvalidFilter).
+			withTypes([][]internal.ExprType{{internal.ExprTypeBool}, {internal.ExprTypeBoolIdentifier}}, result.Type)
+		return nil, internal.TranslationResult{}, bindSource(err, query)
+	}
+	if t.simplified {
+		// the simplified query is translated as written if its literals change the types of operands
+		simplified, err := t.translateFilter(simplify(parsed.Node))
+		if err == nil && (simplified.Type == internal.ExprTypeBool || simplified.Type == internal.ExprTypeBoolIdentifier) {
+			result = simplified
+		}
+	}
+	return parsed, result, nil
+}
+
+// translateFilter translates the condition of a query, recording the conditions which filter rows alike
+// whether false or NULL
+func (t *translator) translateFilter(node ast.Node) (internal.TranslationResult, error) {
+	scoped := *t
+	scoped.filters = map[ast.Node]bool{}
+	scoped.recordFilter(node)
+	return scoped.translate(node)
+}
+
+// recordFilter records the filtering condition, e.g. of a query or of a predicate, along with the operands
+// of its conjunctions and disjunctions, which filter alike whether false or NULL too
+func (t *translator) recordFilter(node ast.Node) {
+	if t.filters == nil {
+		return // not translating a filter, e.g. translating sort keys
+	}
+	for nodes := []ast.Node{node}; len(nodes) > 0; nodes = nodes[1:] {
+		t.filters[nodes[0]] = true
+		if binary, ok := nodes[0].(*ast.BinaryNode); ok && slices.Contains([]string{"and", "&&", "or", "||"}, binary.Operator) {
+			nodes = append(nodes, binary.Left, binary.Right)
+		}
+	}
+}
+
+// inline formats the result with all bind arguments inlined as literals
+func (t *translator) inline(result internal.TranslationResult) string {
+	return internal.BindArgs(result.Expr, func(index int) string {
+		return t.dialect.literal(result.Args[index])
+	})
+}
+
+func (t *translator) translate(node ast.Node) (translated internal.TranslationResult, err error) {
+	switch typed := node.(type) {
+	case *ast.NilNode:
+		return internal.TranslationResult{Expr: "NULL", Type: internal.ExprTypeNil}, nil
+	case *ast.IdentifierNode:
+		translated, _, err = t.translateIdentifier(typed)
+		if err != nil {
+			return internal.TranslationResult{}, err
+		}
+		return translated, t.grouped(node, translated)
+	case *ast.StringNode:
+		t, err := time.Parse(time.RFC3339Nano, typed.Value) // special case for timestamp strings
+		if err == nil {
+			// adjust valid timestamp to UTC in case DB column does not use time zones
+			return internal.Literal(t.UTC(), internal.ExprTypeTimestamp), nil
+		}
+		return internal.Literal(typed.Value, internal.ExprTypeString), nil
+	case *ast.IntegerNode:
+		return internal.Literal(typed.Value, internal.ExprTypeInt), nil
+	case *ast.FloatNode:
+		return internal.Literal(typed.Value, internal.ExprTypeFloat), nil
+	case *ast.BoolNode:
+		return internal.Literal(typed.Value, internal.ExprTypeBool), nil
+	case *ast.BinaryNode:
+		leftExpr, err := t.translate(typed.Left)
+		if err != nil {
+			return internal.TranslationResult{}, err
+		}
+		rightExpr, err := t.translate(typed.Right)
+		if err != nil {
+			return internal.TranslationResult{}, err
+		}
+		if leftExpr, rightExpr, err = t.inferDynamic(typed, leftExpr, rightExpr); err != nil {
+			return internal.TranslationResult{}, err
+		}
+		if typed.Operator == "??" {
+			return t.translateCoalesce(typed, leftExpr, rightExpr)
+		}
+		translated, err = t.translateBinaryOperator(typed, leftExpr, rightExpr)
+		if err != nil || !t.jsonContainment {
+			return translated, err
+		}
+		return t.translateJSONCondition(typed, leftExpr, rightExpr, translated)
+	case *ast.ConditionalNode:
+		return t.translateConditional(typed)
+	case *ast.UnaryNode:
+		if binary, ok := typed.Node.(*ast.BinaryNode); ok && typed.Operator == "not" && binary.Operator == "in" {
+			notIn := &ast.BinaryNode{Operator: "not in", Left: binary.Left, Right: binary.Right}
+			notIn.SetLocation(binary.Location())
+			return t.translate(notIn)
+		}
+		expr, err := t.translate(typed.Node)
+		if err != nil {
+			return internal.TranslationResult{}, err
+		}
+		return t.translateUnaryOperator(typed, expr)
+	case *ast.ArrayNode:
+		return t.translateArray(typed)
+	case *ast.BuiltinNode:
+		if _, ok := predicates[typed.Name]; ok {
+			translated, _, err = t.translatePredicate(typed)
+			return translated, err
+		}
+		return t.translateFunction(typed, typed.Name, typed.Arguments)
+	case *ast.PointerNode, *ast.MemberNode:
+		translated, _, err = t.translateJSONValue(node)
+		return translated, err
+	case *ast.CallNode:
+		switch callee := typed.Callee.(type) {
+		case *ast.IdentifierNode:
+			return t.translateFunction(typed, callee.Value, typed.Arguments)
+		case *ast.MemberNode:
+			return t.translateMethod(typed, callee)
+		}
+		return internal.TranslationResult{}, unsupportedOperation(node, node.String())
+	default:
+		return internal.TranslationResult{}, unsupportedOperation(node, node.String())
+	}
+}
+
+// inferDynamic translates a dynamic JSON operand again as a value of the type inferred from the other operand
+func (t *translator) inferDynamic(node *ast.BinaryNode, leftExpr, rightExpr internal.TranslationResult) (internal.TranslationResult, internal.TranslationResult, error) {
+	var err error
+	switch {
+	case leftExpr.Type == internal.ExprTypeJSONDynamic && rightExpr.Type != internal.ExprTypeJSONDynamic:
+		if inferred, ok := internal.InferredType(rightExpr.Type); ok {
+			scoped := *t
+			scoped.dynamicType = inferred
+			leftExpr, err = scoped.translate(node.Left)
+		}
+	case rightExpr.Type == internal.ExprTypeJSONDynamic && leftExpr.Type != internal.ExprTypeJSONDynamic:
+		if inferred, ok := internal.InferredType(leftExpr.Type); ok {
+			scoped := *t
+			scoped.dynamicType = inferred
+			rightExpr, err = scoped.translate(node.Right)
+		}
+	}
+	return leftExpr, rightExpr, err
+}
+
+// translateArray translates an array literal to a value list, with int and float elements unified to float
+func (t *translator) translateArray(node *ast.ArrayNode) (internal.TranslationResult, error) {
+	elemType := internal.ExprTypeNil
+	elems := make([]string, 0, len(node.Nodes))
+	var args []any
+	for _, elemNode := range node.Nodes {
+		elem, err := t.translate(elemNode)
+		if err != nil {
+			return internal.TranslationResult{}, err
+		}
+		switch {
+		case elem.Type == internal.ExprTypeNil:
+			return internal.TranslationResult{}, unsupportedOperation(elemNode, fmt.Sprintf("nil array element in %v", node))
+		case elemType == internal.ExprTypeNil || elemType == elem.Type:
+			elemType = elem.Type
+		case slices.Contains(numericLiteralTypes, elemType) && slices.Contains(numericLiteralTypes, elem.Type):
+			elemType = internal.ExprTypeFloat
+		default:
+			return internal.TranslationResult{}, unsupportedOperation(elemNode, fmt.Sprintf("mixed array element types in %v", node))
+		}
+		elems = append(elems, elem.Expr)
+		args = append(args, elem.Args...)
+	}
+	return internal.TranslationResult{
+		Expr: fmt.Sprintf("(%v)", strings.Join(elems, ", ")),
+		Type: internal.ArrayOf(elemType),
+		Args: args,
+	}, nil
+}
+
+var numericLiteralTypes = []internal.ExprType{internal.ExprTypeInt, internal.ExprTypeFloat}
+
+// translateJSONValue translates the node along with the spec of its JSON value, which describes the elements
+// of JSON arrays passed to predicates, and is nil for other values
+func (t *translator) translateJSONValue(node ast.Node) (internal.TranslationResult, JSONElement, error) {
+	switch typed := node.(type) {
+	case *ast.PointerNode:
+		if t.element == nil || typed.Name != "" {
+			return internal.TranslationResult{}, nil, unsupportedOperation(node, node.String())
+		}
+		return *t.element, t.elementSpec, nil
+	case *ast.IdentifierNode:
+		translated, jsonEl, err := t.translateIdentifier(typed)
+		if err != nil {
+			return internal.TranslationResult{}, nil, err
+		}
+		return translated, jsonEl, t.grouped(node, translated)
+	case *ast.MemberNode:
+		path, jsonEl, err := t.translateJSON(typed)
+		if err != nil {
+			return internal.TranslationResult{}, nil, err
+		}
+		if _, ok := jsonEl.(relationElement); ok && len(path.joins) > 0 {
+			return internal.TranslationResult{}, nil, unsupportedOperation(node, fmt.Sprintf("relation %v of a related row", node))
+		}
+		translated := path.column
+		if len(path.keys) > 0 {
+			exprType := internal.ExprType(jsonEl.IdentifierType())
+			if exprType == internal.ExprTypeJSONDynamic && t.dynamicType != "" {
+				exprType = t.dynamicType
+			}
+			if t.dynamicTypes != nil && exprType != internal.ExprType(jsonEl.IdentifierType()) {
+				t.dynamicTypes[node] = exprType
+			}
+			translated.Expr, translated.Type = t.jsonExpr(path, jsonEl, exprType), exprType
+			translated.CaseInsensitive = translated.CaseInsensitive && holdsStrings(exprType)
+		}
+		translated = correlate(translated, path.joins)
+		if !elementProperty(typed) {
+			if err := t.grouped(node, translated); err != nil {
+				return internal.TranslationResult{}, nil, err
+			}
+		}
+		return translated, jsonEl, nil
+	case *ast.BuiltinNode:
+		if typed.Name == "filter" {
+			return t.translatePredicate(typed)
+		}
+	}
+	translated, err := t.translate(node)
+	return translated, nil, err
+}
+
+// jsonExpr formats access to the JSON element at the path, converting timestamps from the format in which they are stored
+func (t *translator) jsonExpr(path jsonPath, jsonEl JSONElement, exprType internal.ExprType) string {
+	if exprType != internal.ExprTypeTimestampIdentifier {
+		return t.dialect.jsonExpr(path.column.Expr, path.keys, exprType)
+	}
+	format := jsonTimestampFormat(jsonEl)
+	return t.dialect.jsonTimestamp(t.dialect.jsonExpr(path.column.Expr, path.keys, format.storedType()), format)
+}
+
+// jsonPath is the path to an element within a JSON column, or within a JSON array element,
+// where the column may belong to a related row joined by correlated subqueries
+type jsonPath struct {
+	column internal.TranslationResult
+	keys   []any
+	joins  []relationJoin
+}
+
+// jsonPathKey matches keys which need no quotes in JSON paths, unlike e.g. $id, which is a variable in Postgres
+var jsonPathKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// jsonPathExpression formats the path as a SQL/JSON path expression, e.g. $.a."b c"[0]
+func jsonPathExpression(path []any) string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	for _, key := range path {
+		switch key := key.(type) {
+		case int:
+			sb.WriteString(fmt.Sprintf("[%d]", key))
+		case string:
+			sb.WriteString(".")
+			if jsonPathKey.MatchString(key) {
+				sb.WriteString(key)
+			} else {
+				sb.WriteString(strconv.Quote(key))
+			}
+		}
+	}
+	return sb.String()
+}
+
+func (t *translator) translateJSON(node ast.Node) (jsonPath, JSONElement, error) {
+	switch typed := node.(type) {
+	case *ast.IdentifierNode:
+		column, jsonEl, err := t.translateIdentifier(typed)
+		return jsonPath{column: column}, jsonEl, err
+	case *ast.PointerNode:
+		column, jsonEl, err := t.translateJSONValue(typed)
+		return jsonPath{column: column}, jsonEl, err
+	case *ast.MemberNode:
+		path, jsonEl, err := t.translateJSON(typed.Node)
+		if err != nil {
+			return jsonPath{}, nil, err
+		}
+		if related, ok := jsonEl.(relationElement); ok {
+			return t.translateRelated(typed, path, related)
+		}
+		if !internal.IsJSON(path.column.Type) {
+			return jsonPath{}, nil, unsupportedOperation(typed, fmt.Sprintf("value at '%v' is not a json object", typed.Node))
+		}
+		switch property := typed.Property.(type) {
+		case *ast.StringNode:
+			switch object := jsonEl.(type) {
+			case JSONTree:
+				var ok bool
+				if jsonEl, ok = object[property.Value]; !ok {
+					return jsonPath{}, nil, unknownIdentifier(typed, fmt.Sprintf("json object at '%v' does not contain field '%v'", typed.Node, property.Value))
+				}
+			case jsonDynamic:
+				if !dynamicKey(property.Value) {
+					return jsonPath{}, nil, unsupportedOperation(typed.Property, fmt.Sprintf("json key %q contains quotes, backslashes or control characters", property.Value))
+				}
+				if object.keys != nil && !object.keys.MatchString(property.Value) {
+					return jsonPath{}, nil, unknownIdentifier(typed, fmt.Sprintf("json object at '%v' does not allow field '%v'", typed.Node, property.Value))
+				}
+			default:
+				return jsonPath{}, nil, unsupportedOperation(typed, fmt.Sprintf("value at '%v' is not a json object", typed.Node))
+			}
+			path.keys = append(slices.Clone(path.keys), property.Value)
+		case *ast.IntegerNode:
+			array, ok := jsonEl.(jsonArray)
+			if !ok {
+				return jsonPath{}, nil, unsupportedOperation(typed, fmt.Sprintf("value at '%v' is not a json array", typed.Node))
+			}
+			if property.Value < 0 {
+				return jsonPath{}, nil, unsupportedOperation(typed.Property, fmt.Sprintf("json array index needs to be non-negative, instead found %v", property.Value))
+			}
+			jsonEl = array.element
+			path.keys = append(slices.Clone(path.keys), property.Value)
+		default:
+			return jsonPath{}, nil, unsupportedOperation(typed.Property, fmt.Sprintf("json key needs to be string or array index, instead found %v", typed.Property))
+		}
+		return path, jsonEl, nil
+	default:
+		return jsonPath{}, nil, unsupportedOperation(node, fmt.Sprintf("json %v", node))
+	}
+}
+
+// dynamicKey reports whether the key of a dynamic JSON object can be quoted in the JSON paths of all dialects
+func dynamicKey(key string) bool {
+	return key != "" && utf8.ValidString(key) && !strings.ContainsFunc(key, func(r rune) bool {
+		return r == '"' || r == '\\' || unicode.IsControl(r)
+	})
+}
+
+func (t *translator) translateIdentifier(node *ast.IdentifierNode) (translated internal.TranslationResult, jsonEl JSONElement, err error) {
+	identifier, ok := t.identifier(node.Value)
+	if !ok {
+		return internal.TranslationResult{}, nil, unknownIdentifier(node, node.Value)
+	}
+	if t.invalidIdentifier != nil {
+		return internal.TranslationResult{}, nil, t.invalidIdentifier
+	}
+	if t.relatedRows && len(identifier.qualifiedName()) < 2 {
+		// the subquery over related rows would resolve the column within the foreign table, if it has one of the name
+		return internal.TranslationResult{}, nil, unsupportedOperation(node, fmt.Sprintf("%v within a predicate over related rows needs to be qualified by its table", node.Value))
+	}
+	translated, jsonEl = t.translateColumn(identifier)
+	return translated, jsonEl, nil
+}
+
+// translateColumn translates the qualified column of the identifier along with its JSON spec,
+// or the column joined to the foreign table along with the spec of the relation
+func (t *translator) translateColumn(identifier Identifier) (internal.TranslationResult, JSONElement) {
+	parts := identifier.qualifiedName()
+	for i, part := range parts {
+		parts[i] = t.dialect.identifier(part)
+	}
+	translated := internal.TranslationResult{
+		Expr:            strings.Join(parts, "."),
+		Type:            internal.ExprType(identifier.Type),
+		CaseInsensitive: (t.caseInsensitive || identifier.CaseInsensitive) && holdsStrings(internal.ExprType(identifier.Type)),
+	}
+	if identifier.Type == IdentifierTypeRelation {
+		related := relationElement{relation: identifier.Relation, column: translated}
+		translated.Type = internal.ExprType(related.IdentifierType())
+		return translated, related
+	}
+	jsonSpec := identifier.JSONSpec
+	if jsonSpec == nil && identifier.Type == IdentifierTypeJSON {
+		jsonSpec = JSONTree{} // no known properties
+	}
+	return translated, jsonSpec
+}
+
+func (t *translator) identifier(exprName string) (Identifier, bool) {
+	index := slices.IndexFunc(t.allowedIdentifiers, func(id Identifier) bool {
+		return id.ExprName == exprName
+	})
+	if index == -1 {
+		return Identifier{}, false
+	}
+	return t.allowedIdentifiers[index], true
+}
+
+func (t *translator) function(name string) (internal.FunctionDescriptor, bool) {
+	if fn, ok := t.functions[name]; ok {
+		return fn.descriptor(), true
+	}
+	if name == "now" && t.clock != nil {
+		return internal.FunctionDescriptor{
+			FnTranslator: func([]internal.TranslationResult) (internal.TranslationResult, error) {
+				return internal.Literal(t.clock().UTC(), internal.ExprTypeTimestamp), nil
+			},
+		}, true
+	}
+	if descriptor, ok := t.dialect.functionOverrides()[name]; ok {
+		return descriptor, true
+	}
+	descriptor, ok := builtinFunctions[name]
+	return descriptor, ok
+}
+
+func (t *translator) translateFunction(node ast.Node, name string, argNodes []ast.Node) (internal.TranslationResult, error) {
+	if descriptor, ok := aggregateFunctions[name]; ok && t.having {
+		if _, custom := t.functions[name]; !custom {
+			return t.translateAggregate(node, name, descriptor, argNodes)
+		}
+	}
+	if castType, ok := castTypes[name]; ok {
+		if _, custom := t.functions[name]; !custom {
+			return t.translateCast(node, castType, argNodes)
+		}
+	}
+	descriptor, ok := t.function(name)
+	if !ok {
+		return internal.TranslationResult{}, unsupportedOperation(node, fmt.Sprintf("function %v", name))
+	}
+	if _, custom := t.functions[name]; name == "date" && !custom && len(argNodes) == 1 {
+		if str, ok := argNodes[0].(*ast.StringNode); ok {
+			// parse RFC3339 strings like the other layouts, rather than truncating them like timestamps
+			result, err := descriptor.FnTranslator([]internal.TranslationResult{internal.Literal(str.Value, internal.ExprTypeString)})
+			if err != nil {
+				return internal.TranslationResult{}, unsupportedOperation(node, fmt.Sprintf("%v: %v", node, err))
+			}
+			return result, nil
+		}
+	}
+	return t.callFunction(node, descriptor, argNodes)
+}
+
+// castTypes are the types of the cast functions of dynamic JSON values
+var castTypes = map[string]internal.ExprType{
+	"int":    internal.ExprTypeIntIdentifier,
+	"float":  internal.ExprTypeFloatIdentifier,
+	"string": internal.ExprTypeStringIdentifier,
+	"bool":   internal.ExprTypeBoolIdentifier,
+}
+
+// translateCast translates the dynamic JSON values of the argument as values of the cast type,
+// which the argument needs to result in
+func (t *translator) translateCast(node ast.Node, castType internal.ExprType, argNodes []ast.Node) (internal.TranslationResult, error) {
+	expected := [][]internal.ExprType{{internal.ExprTypeJSONDynamic}}
+	if len(argNodes) != 1 {
+		return internal.TranslationResult{}, unsupportedOperation(node, node.String()).withTypes(expected)
+	}
+	scoped := *t
+	scoped.dynamicType = castType
+	arg, err := scoped.translate(argNodes[0])
+	if err != nil {
+		return internal.TranslationResult{}, err
+	}
+	if literalType, _ := internal.LiteralType(castType); arg.Type != castType && arg.Type != literalType {
+		return internal.TranslationResult{}, unsupportedOperation(node, node.String()).withTypes(expected, arg.Type)
+	}
+	return arg, nil
+}
+
+// translateMethod translates the date part accessors of timestamps, e.g. createdAt.Year(),
+// as functions of the timestamp
+func (t *translator) translateMethod(node *ast.CallNode, callee *ast.MemberNode) (internal.TranslationResult, error) {
+	name, ok := callee.Property.(*ast.StringNode)
+	if !ok || !callee.Method {
+		return internal.TranslationResult{}, unsupportedOperation(node, node.String())
+	}
+	descriptor, ok := t.dialect.dateParts()[name.Value]
+	if !ok || len(node.Arguments) > 0 {
+		return internal.TranslationResult{}, unsupportedOperation(node, fmt.Sprintf("method %v", name.Value))
+	}
+	return t.callFunction(node, descriptor, []ast.Node{callee.Node})
+}
+
+func (t *translator) callFunction(node ast.Node, descriptor internal.FunctionDescriptor, argNodes []ast.Node) (internal.TranslationResult, error) {
+	args := make([]internal.TranslationResult, 0, len(argNodes))
+	argTypes := make([]internal.ExprType, 0, len(argNodes))
+	for _, argNode := range argNodes {
+		arg, err := t.translate(argNode)
+		if err != nil {
+			return internal.TranslationResult{}, err
+		}
+		args = append(args, arg)
+		argTypes = append(argTypes, arg.Type)
+	}
+	if !descriptor.AcceptsArgs(argTypes) {
+		return internal.TranslationResult{}, unsupportedOperation(node, node.String()).withTypes(descriptor.ArgTypes, argTypes...)
+	}
+	result, err := descriptor.FnTranslator(args)
+	if err != nil {
+		return internal.TranslationResult{}, unsupportedOperation(node, fmt.Sprintf("%v: %v", node, err))
+	}
+	return result, nil
+}
+
+// predicates are templates of the array predicate builtins, with the table of the array elements as {0}
+// and the condition on an element as {1}. The filter template is completed by the aggregation of the elements.
+var predicates = map[string]string{
+	"any":    "(EXISTS (SELECT 1 FROM {0} WHERE {1}))",
+	"all":    "(NOT EXISTS (SELECT 1 FROM {0} WHERE {1} IS NOT TRUE))",
+	"none":   "(NOT EXISTS (SELECT 1 FROM {0} WHERE {1}))",
+	"one":    "((SELECT count(*) FROM {0} WHERE {1}) = 1)",
+	"filter": "(SELECT %v FROM {0} WHERE {1})",
+}
+
+// translatePredicate translates the closure of the array predicate as a condition on each element of the array
+// within a subquery. The closure is translated by a copy of the translator referencing the element as #.
+// Filtered JSON arrays are returned along with their spec.
+func (t *translator) translatePredicate(node *ast.BuiltinNode) (internal.TranslationResult, JSONElement, error) {
+	if len(node.Arguments) != 2 {
+		return internal.TranslationResult{}, nil, unsupportedOperation(node, node.String())
+	}
+	closure, ok := node.Arguments[1].(*ast.ClosureNode)
+	if !ok {
+		return internal.TranslationResult{}, nil, unsupportedOperation(node, node.String())
+	}
+	array, spec, err := t.translateJSONValue(node.Arguments[0])
+	if err != nil {
+		return internal.TranslationResult{}, nil, err
+	}
+	if related, ok := spec.(relationElement); ok {
+		return t.translateRelationPredicate(node, closure, related)
+	}
+	scoped := *t
+	scoped.depth++
+	alias := fmt.Sprintf("elem%d", scoped.depth)
+	var table, elem string
+	elemType, ok := internal.ElemType(array.Type)
+	arraySpec, isJSON := spec.(jsonArray)
+	switch {
+	case ok:
+		table, elem = t.dialect.arrayElements(array.Expr, elemType, alias)
+		scoped.elementSpec = nil
+	case isJSON:
+		elemType = internal.ExprType(arraySpec.element.IdentifierType())
+		storedType := elemType
+		if elemType == internal.ExprTypeTimestampIdentifier {
+			storedType = jsonTimestampFormat(arraySpec.element).storedType()
+		}
+		table, elem = t.dialect.jsonArrayElements(array.Expr, storedType, alias)
+		scoped.elementSpec = arraySpec.element
+	default:
+		return internal.TranslationResult{}, nil, unsupportedOperation(node, fmt.Sprintf("%v of non-array %v", node.Name, node.Arguments[0])).
+			withTypes(nil, array.Type)
+	}
+	condElem := elem // aggregated elements of JSON arrays keep the stored timestamps
+	if isJSON && elemType == internal.ExprTypeTimestampIdentifier {
+		condElem = t.dialect.jsonTimestamp(elem, jsonTimestampFormat(arraySpec.element))
+	}
+	scoped.element = &internal.TranslationResult{Expr: condElem, Type: elemType, CaseInsensitive: array.CaseInsensitive && holdsStrings(elemType)}
+	scoped.recordFilter(closure.Node) // elements match only if the condition is true, even for all
+	condition, err := scoped.translate(closure.Node)
+	if err != nil {
+		return internal.TranslationResult{}, nil, err
+	}
+	if condition.Type != internal.ExprTypeBool && condition.Type != internal.ExprTypeBoolIdentifier {
+		return internal.TranslationResult{}, nil, unsupportedOperation(closure.Node, fmt.Sprintf("non-boolean %v condition %v", node.Name, closure.Node)).
+			withTypes([][]internal.ExprType{{internal.ExprTypeBool}, {internal.ExprTypeBoolIdentifier}}, condition.Type)
+	}
+	if node.Name == "all" {
+		condition = parenthesize(condition, precedenceComparison) // the condition precedes IS NOT TRUE
+	}
+	template := predicates[node.Name]
+	resultType := internal.ExprTypeBool
+	var resultSpec JSONElement
+	if node.Name == "filter" {
+		aggregate := t.dialect.arrayAggregate(elem)
+		if isJSON {
+			aggregate = t.dialect.jsonArrayAggregate(elem)
+		}
+		template = fmt.Sprintf(template, aggregate)
+		resultType = array.Type
+		resultSpec = spec
+	}
+	result := internal.FormatTemplate(template, internal.TranslationResult{Expr: table, Args: array.Args}, condition)
+	result.Type = resultType
+	result.CaseInsensitive = array.CaseInsensitive && node.Name == "filter"
+	return result, resultSpec, nil
+}
+
+// translateConditional translates the ternary operator to CASE, with the branches unified to a common type
+func (t *translator) translateConditional(node *ast.ConditionalNode) (internal.TranslationResult, error) {
+	var operands []internal.TranslationResult
+	for _, operandNode := range []ast.Node{node.Cond, node.Exp1, node.Exp2} {
+		operand, err := t.translate(operandNode)
+		if err != nil {
+			return internal.TranslationResult{}, err
+		}
+		operands = append(operands, operand)
+	}
+	cond, exp1, exp2 := operands[0], operands[1], operands[2]
+	resultType, ok := internal.UnifiedType(exp1.Type, exp2.Type)
+	if !ok || cond.Type != internal.ExprTypeBool && cond.Type != internal.ExprTypeBoolIdentifier {
+		return internal.TranslationResult{}, unsupportedOperation(node, node.String()).withTypes(nil, cond.Type, exp1.Type, exp2.Type)
+	}
+	return t.recordUnified(node, internal.TranslationResult{
+		Expr:            fmt.Sprintf("CASE WHEN %v THEN %v ELSE %v END", cond.Expr, exp1.Expr, exp2.Expr),
+		Type:            resultType,
+		Args:            slices.Concat(cond.Args, exp1.Args, exp2.Args),
+		CaseInsensitive: (exp1.CaseInsensitive || exp2.CaseInsensitive) && holdsStrings(resultType),
+	}), nil
+}
+
+// translateCoalesce translates the nil-coalescing operator to COALESCE, with the operands unified to a common type
+func (t *translator) translateCoalesce(node *ast.BinaryNode, leftExpr, rightExpr internal.TranslationResult) (internal.TranslationResult, error) {
+	resultType, ok := internal.UnifiedType(leftExpr.Type, rightExpr.Type)
+	if !ok {
+		return internal.TranslationResult{}, unsupportedOperation(node, node.String()).withTypes(nil, leftExpr.Type, rightExpr.Type)
+	}
+	return t.recordUnified(node, internal.TranslationResult{
+		Expr:            fmt.Sprintf("COALESCE(%v, %v)", leftExpr.Expr, rightExpr.Expr),
+		Type:            resultType,
+		Args:            slices.Concat(leftExpr.Args, rightExpr.Args),
+		CaseInsensitive: (leftExpr.CaseInsensitive || rightExpr.CaseInsensitive) && holdsStrings(resultType),
+	}), nil
+}
+
+// recordUnified records the result of the conditional or nil-coalescing expression, if requested by the Evaluator
+func (t *translator) recordUnified(node ast.Node, result internal.TranslationResult) internal.TranslationResult {
+	if t.unified != nil {
+		t.unified[node] = result
+	}
+	return result
+}
+
+// holdsStrings reports whether expressions of the type are strings, or JSON values and arrays which can contain strings,
+// so that string operators can ignore their case
+func holdsStrings(exprType internal.ExprType) bool {
+	elemType, isArray := internal.ElemType(exprType)
+	return isString(exprType) || internal.IsJSON(exprType) || isArray && elemType == internal.ExprTypeStringIdentifier
+}
+
+func isString(exprType internal.ExprType) bool {
+	return exprType == internal.ExprTypeString || exprType == internal.ExprTypeStringIdentifier
+}
+
+// binaryOperator returns the descriptor of the operator, which is case-insensitive for string operands
+// if either of them ignores case
+func (t *translator) binaryOperator(op string, leftExpr, rightExpr internal.TranslationResult) (internal.BinaryOperatorDescriptor, bool) {
+	if (leftExpr.CaseInsensitive || rightExpr.CaseInsensitive) && isString(leftExpr.Type) && isString(rightExpr.Type) {
+		if descriptor, ok := t.dialect.caseInsensitiveOperators()[op]; ok {
+			return descriptor, true
+		}
+		if descriptor, ok := caseInsensitiveBinaryOperators[op]; ok {
+			return descriptor, true
+		}
+	}
+	if descriptor, ok := t.dialect.operatorOverrides()[op]; ok {
+		return descriptor, true
+	}
+	descriptor, ok := binaryOperators[op]
+	return descriptor, ok
+}
+
+func (t *translator) translateBinaryOperator(node *ast.BinaryNode, leftExpr, rightExpr internal.TranslationResult) (internal.TranslationResult, error) {
+	descriptor, ok := t.binaryOperator(node.Operator, leftExpr, rightExpr)
+	if !ok {
+		return internal.TranslationResult{}, unsupportedOperation(node, node.String())
+	}
+	if len(descriptor.TypeConstraints) > 0 &&
+		!slices.Contains(descriptor.TypeConstraints, internal.BinaryOperatorTypeConstraint{Left: leftExpr.Type, Right: rightExpr.Type}) {
+		expected := make([][]internal.ExprType, 0, len(descriptor.TypeConstraints))
+		for _, constraint := range descriptor.TypeConstraints {
+			expected = append(expected, []internal.ExprType{constraint.Left, constraint.Right})
+		}
+		return internal.TranslationResult{}, unsupportedOperation(node, node.String()).withTypes(expected, leftExpr.Type, rightExpr.Type)
+	}
+	precedence, ok := binaryPrecedences[node.Operator]
+	if !ok || !t.simplified {
+		result := descriptor.OpTranslator(leftExpr, rightExpr)
+		result.Expr = fmt.Sprintf("(%v)", result.Expr)
+		return result, nil
+	}
+	switch precedence {
+	case precedenceOr, precedenceAnd: // associative
+		leftExpr, rightExpr = parenthesize(leftExpr, precedence-1), parenthesize(rightExpr, precedence-1)
+	case precedenceAdditive, precedenceMultiplicative: // left-associative
+		leftExpr, rightExpr = parenthesize(leftExpr, precedence-1), parenthesize(rightExpr, precedence)
+	default:
+		leftExpr, rightExpr = parenthesize(leftExpr, precedence), parenthesize(rightExpr, precedence)
+	}
+	result := descriptor.OpTranslator(leftExpr, rightExpr)
+	result.Precedence = precedence
+	return result, nil
+}
+
+// Precedences of the SQL operators translating the operators of queries, which are the lowest in any dialect
+const (
+	precedenceAtomic = iota
+	precedenceOr
+	precedenceAnd
+	precedenceNot
+	precedenceComparison
+	precedenceAdditive
+	precedenceMultiplicative
+	precedenceUnary
+)
+
+// binaryPrecedences are the precedences of the binary operators, which are parenthesized regardless of the precedence
+// if missing, e.g. exponentiation which is a function in some dialects. The negation of membership is NOT IN,
+// or NOT applied to membership in some dialects.
+var binaryPrecedences = map[string]int{
+	"or": precedenceOr, "||": precedenceOr,
+	"and": precedenceAnd, "&&": precedenceAnd,
+	"not in": precedenceNot, "==": precedenceComparison, "!=": precedenceComparison,
+	"<": precedenceComparison, ">": precedenceComparison, "<=": precedenceComparison, ">=": precedenceComparison,
+	"in": precedenceComparison, "matches": precedenceComparison,
+	"contains": precedenceComparison, "startsWith": precedenceComparison, "endsWith": precedenceComparison,
+	"+": precedenceAdditive, "-": precedenceAdditive,
+	"*": precedenceMultiplicative, "/": precedenceMultiplicative, "%": precedenceMultiplicative,
+}
+
+var unaryPrecedences = map[string]int{
+	"not": precedenceNot, "!": precedenceNot,
+	"-": precedenceUnary,
+}
+
+// parenthesize wraps the expression in parentheses if its outermost operator doesn't bind tighter than the precedence
+func parenthesize(result internal.TranslationResult, precedence int) internal.TranslationResult {
+	if result.Precedence == precedenceAtomic || result.Precedence > precedence {
+		return result
+	}
+	result.Expr = fmt.Sprintf("(%v)", result.Expr)
+	result.Precedence = precedenceAtomic
+	return result
+}
+
+// mirroredComparisons are the comparison operators with swapped operands, e.g. 3 < a as a > 3
+var mirroredComparisons = map[string]string{"==": "==", "!=": "!=", "<": ">", ">": "<", "<=": ">=", ">=": "<="}
+
+// translateJSONCondition translates the comparison of a JSON property with a literal to a condition on the JSON column,
+// keeping the translated comparison of other operands
+func (t *translator) translateJSONCondition(node *ast.BinaryNode, leftExpr, rightExpr, translated internal.TranslationResult) (internal.TranslationResult, error) {
+	op, ok := mirroredComparisons[node.Operator]
+	if !ok || op == "!=" || !t.filters[node] {
+		return translated, nil // JSONPath filters match null properties for !=, and negated JSON conditions match missing ones
+	}
+	propertyNode, property, value := node.Right, rightExpr, leftExpr
+	if !internal.IsLiteral(leftExpr) {
+		propertyNode, property, value, op = node.Left, leftExpr, rightExpr, node.Operator
+	}
+	if _, ok := propertyNode.(*ast.MemberNode); !ok || !internal.IsLiteral(value) || value.Type == internal.ExprTypeTimestamp ||
+		(property.CaseInsensitive && value.Type == internal.ExprTypeString) {
+		return translated, nil
+	}
+	path, _, err := t.translateJSON(propertyNode)
+	if err != nil {
+		return internal.TranslationResult{}, err
+	}
+	if len(path.keys) == 0 || len(path.joins) > 0 {
+		return translated, nil // columns of related rows, or properties of JSON columns of single related rows
+	}
+	condition, ok := t.dialect.jsonCondition(path.column, path.keys, op, value.Args[0])
+	if !ok {
+		return translated, nil
+	}
+	condition.Type = translated.Type
+	if t.simplified {
+		condition.Precedence = precedenceComparison
+		return condition, nil
+	}
+	condition.Expr = fmt.Sprintf("(%v)", condition.Expr)
+	return condition, nil
+}
+
+func (t *translator) translateUnaryOperator(node *ast.UnaryNode, expr internal.TranslationResult) (internal.TranslationResult, error) {
+	descriptor, ok := unaryOperators[node.Operator]
+	if !ok {
+		return internal.TranslationResult{}, unsupportedOperation(node, node.String())
+	}
+	if len(descriptor.TypeConstraints) > 0 && !slices.Contains(descriptor.TypeConstraints, expr.Type) {
+		expected := make([][]internal.ExprType, 0, len(descriptor.TypeConstraints))
+		for _, constraint := range descriptor.TypeConstraints {
+			expected = append(expected, []internal.ExprType{constraint})
+		}
+		return internal.TranslationResult{}, unsupportedOperation(node, node.String()).withTypes(expected, expr.Type)
+	}
+	precedence, ok := unaryPrecedences[node.Operator]
+	if !ok || !t.simplified {
+		result := descriptor.OpTranslator(expr)
+		result.Expr = fmt.Sprintf("(%v)", result.Expr)
+		return result, nil
+	}
+	result := descriptor.OpTranslator(parenthesize(expr, precedence))
+	if !internal.IsLiteral(result) {
+		result.Precedence = precedence
+	}
+	return result, nil
 }